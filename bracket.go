@@ -0,0 +1,61 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+// bracketPairs maps each bracket to its partner.
+var bracketPairs = map[rune]rune{
+	'(': ')', ')': '(',
+	'[': ']', ']': '[',
+	'{': '}', '}': '{',
+}
+
+// isOpeningBracket reports whether r is an opening bracket.
+func isOpeningBracket(r rune) bool {
+	return r == '(' || r == '[' || r == '{'
+}
+
+// MatchingBracket returns the position of the bracket that matches the
+// bracket under the cursor, (), [] and {} being supported. The brackets of
+// the same kind in between are balanced, across lines. found is false if the
+// cursor isn't on a bracket or if the bracket is unbalanced.
+func (v *View) MatchingBracket() (x, y int, found bool) {
+	if v.cy >= v.lines.len() || v.cx >= len(v.lines.line(v.cy)) {
+		return 0, 0, false
+	}
+	open := v.lines.line(v.cy)[v.cx].chr
+	close, ok := bracketPairs[open]
+	if !ok {
+		return 0, 0, false
+	}
+
+	dir := 1
+	if !isOpeningBracket(open) {
+		dir = -1
+	}
+	depth := 0
+	x, y = v.cx, v.cy
+	for {
+		x += dir
+		for x < 0 || x >= len(v.lines.line(y)) {
+			if y += dir; y < 0 || y >= v.lines.len() {
+				return 0, 0, false
+			}
+			x = 0
+			if dir < 0 {
+				x = len(v.lines.line(y)) - 1
+			}
+		}
+
+		switch v.lines.line(y)[x].chr {
+		case open:
+			depth++
+		case close:
+			if depth == 0 {
+				return x, y, true
+			}
+			depth--
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "testing"
+
+func TestMatchingBracket(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []string
+		cx, cy       int
+		wantX, wantY int
+		wantFound    bool
+	}{
+		{"forward", []string{"f(a, b)"}, 1, 0, 6, 0, true},
+		{"backward", []string{"f(a, b)"}, 6, 0, 1, 0, true},
+		{"nested", []string{"((a)(b))"}, 0, 0, 7, 0, true},
+		{"nested inner", []string{"((a)(b))"}, 4, 0, 6, 0, true},
+		{"nested backward", []string{"{[()]}"}, 4, 0, 1, 0, true},
+		{"other kinds ignored", []string{"(]{)"}, 0, 0, 3, 0, true},
+		{"cross line", []string{"func() {", "\tif x {", "\t}", "}"}, 7, 0, 0, 3, true},
+		{"cross line backward", []string{"func() {", "\tif x {", "\t}", "}"}, 1, 2, 6, 1, true},
+		{"empty lines", []string{"[", "", "]"}, 0, 2, 0, 0, true},
+		{"unbalanced", []string{"((a)"}, 0, 0, 0, 0, false},
+		{"unbalanced backward", []string{"a))"}, 2, 0, 0, 0, false},
+		{"not a bracket", []string{"(a)"}, 1, 0, 0, 0, false},
+		{"end of line", []string{"(a)"}, 3, 0, 0, 0, false},
+		{"empty buffer", nil, 0, 0, 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			x, y, found := v.MatchingBracket()
+			if x != tt.wantX || y != tt.wantY || found != tt.wantFound {
+				t.Errorf("Expected (%d, %d, %v) got: (%d, %d, %v)", tt.wantX, tt.wantY, tt.wantFound, x, y, found)
+			}
+		})
+	}
+}
+
+func TestHighlightMatchingBracket(t *testing.T) {
+	v := newTestView(20, 5, "f(a[0])", "(")
+	v.HighlightMatchingBracket = true
+	v.MatchingBracketFgColor, v.MatchingBracketBgColor = ColorBlack, ColorYellow
+	hlStyle := getTcellStyle(ColorBlack, ColorYellow, OutputNormal)
+
+	for _, tt := range []struct {
+		cx, cy int
+		want   []string // 'x' marks the highlighted cells
+	}{
+		{1, 0, []string{" x    x", " "}},
+		{5, 0, []string{"   x x ", " "}},
+		{2, 0, []string{"       ", " "}},
+		{0, 1, []string{"       ", " "}},
+	} {
+		v.SetCursor(tt.cx, tt.cy)
+		drawTestView(t, v)
+		for y, line := range tt.want {
+			for x, m := range line {
+				_, st := viewCell(v, x, y)
+				if hl := st == hlStyle; hl != (m == 'x') {
+					t.Errorf("Cursor at (%d, %d): expected highlight of (%d, %d) to be %v got: %v", tt.cx, tt.cy, x, y, m == 'x', hl)
+				}
+			}
+		}
+	}
+}
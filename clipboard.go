@@ -0,0 +1,68 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+// Clipboard is the clipboard used by EditCopy, EditCut and EditPaste, e.g.
+// the clipboard of the system. gocui doesn't provide one: the application
+// sets Gui.Clipboard to an implementation that suits it.
+type Clipboard interface {
+	// Read returns the content of the clipboard.
+	Read() (string, error)
+
+	// Write replaces the content of the clipboard.
+	Write(s string) error
+}
+
+// clipboard returns the clipboard of the Gui of the view, or nil if the kill
+// ring is used instead.
+func (v *View) clipboard() Clipboard {
+	if v.gui == nil {
+		return nil
+	}
+	return v.gui.Clipboard
+}
+
+// EditCopy writes the selected text to Gui.Clipboard, or adds it to the kill
+// ring if the Gui has no clipboard. It does nothing if no text is selected
+// and returns the error of the clipboard.
+func (v *View) EditCopy() error {
+	text := v.SelectedText()
+	if text == "" {
+		return nil
+	}
+	if c := v.clipboard(); c != nil {
+		return c.Write(text)
+	}
+	v.pushKill(text)
+	return nil
+}
+
+// EditCut copies the selected text like EditCopy, then deletes it. The
+// text isn't deleted if it couldn't be copied.
+func (v *View) EditCut() error {
+	if err := v.EditCopy(); err != nil {
+		return err
+	}
+	v.DeleteSelection()
+	return nil
+}
+
+// EditPaste writes the content of Gui.Clipboard at the cursor position, or
+// the last killed text like EditYank if the Gui has no clipboard. Like
+// EditWriteString, it replaces the selected text if there is a selection.
+// It returns the error of the clipboard.
+func (v *View) EditPaste() error {
+	c := v.clipboard()
+	if c == nil {
+		v.EditYank()
+		return nil
+	}
+	text, err := c.Read()
+	if err != nil {
+		return err
+	}
+	v.EditWriteString(text)
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeClipboard is a Clipboard keeping its content in memory.
+type fakeClipboard struct {
+	text string
+	err  error
+}
+
+func (c *fakeClipboard) Read() (string, error) {
+	return c.text, c.err
+}
+
+func (c *fakeClipboard) Write(s string) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.text = s
+	return nil
+}
+
+func TestClipboard(t *testing.T) {
+	v := newTestView(20, 5, "hello world", "second line")
+	c := &fakeClipboard{}
+	v.gui.Clipboard = c
+
+	v.SetSelection(6, 0, 3, 1)
+	if err := v.EditCopy(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "world\nsec"; c.text != want {
+		t.Errorf("Expected the clipboard to hold %q got: %q", want, c.text)
+	}
+	if v.LastKill() != "" {
+		t.Errorf("Expected the kill ring to be unused got: %q", v.LastKill())
+	}
+
+	// Round-trip
+	v.ClearSelection()
+	v.SetCursor(11, 1)
+	if err := v.EditPaste(); err != nil {
+		t.Fatal(err)
+	}
+	assertBuffer(t, v, 3, 2, "hello world", "second lineworld", "sec")
+
+	// Nothing is cut without a selection
+	if err := v.EditCut(); err != nil {
+		t.Fatal(err)
+	}
+	v.SetSelection(0, 0, 5, 0)
+	if err := v.EditCut(); err != nil {
+		t.Fatal(err)
+	}
+	assertBuffer(t, v, 0, 0, " world", "second lineworld", "sec")
+	if c.text != "hello" {
+		t.Errorf("Expected the clipboard to hold %q got: %q", "hello", c.text)
+	}
+
+	// Errors are returned and nothing is changed
+	c.err = errors.New("no clipboard")
+	v.SetSelection(0, 1, 6, 1)
+	if err := v.EditCut(); err != c.err {
+		t.Errorf("Expected the error of the clipboard got: %v", err)
+	}
+	if err := v.EditPaste(); err != c.err {
+		t.Errorf("Expected the error of the clipboard got: %v", err)
+	}
+	assertBuffer(t, v, 0, 0, " world", "second lineworld", "sec")
+}
+
+func TestClipboardKillRing(t *testing.T) {
+	v := newTestView(20, 5, "hello world")
+	v.SetSelection(0, 0, 5, 0)
+	if err := v.EditCopy(); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.LastKill(); got != "hello" {
+		t.Errorf("Expected the kill ring to hold %q got: %q", "hello", got)
+	}
+
+	v.ClearSelection()
+	v.SetCursor(11, 0)
+	if err := v.EditPaste(); err != nil {
+		t.Fatal(err)
+	}
+	assertBuffer(t, v, 16, 0, "hello worldhello")
+}
@@ -6,6 +6,8 @@ package gocui
 
 import (
 	"errors"
+	"strings"
+	"unicode"
 )
 
 // Editor interface must be satisfied by gocui editors.
@@ -13,6 +15,16 @@ type Editor interface {
 	Edit(v *View, key Key, ch rune, mod Modifier)
 }
 
+// FallthroughEditor is an Editor that reports whether it handled a key, see
+// View.EditorFallthrough.
+type FallthroughEditor interface {
+	Editor
+
+	// HandleKey works like Edit, it returns false if the key wasn't
+	// handled.
+	HandleKey(v *View, key Key, ch rune, mod Modifier) bool
+}
+
 // The EditorFunc type is an adapter to allow the use of ordinary functions as
 // Editors. If f is a function with the appropriate signature, EditorFunc(f)
 // is an Editor object that calls f.
@@ -28,8 +40,13 @@ var DefaultEditor Editor = EditorFunc(simpleEditor)
 
 // simpleEditor is used as the default gocui editor.
 func simpleEditor(v *View, key Key, ch rune, mod Modifier) {
+	v.beginEdit()
+	defer v.endEdit()
+
 	if ch != 0 && mod == 0 {
-		v.EditWrite(ch)
+		if !v.autoPairWrite(ch) {
+			v.EditWrite(ch)
+		}
 		return
 	}
 
@@ -37,7 +54,9 @@ func simpleEditor(v *View, key Key, ch rune, mod Modifier) {
 	case KeySpace:
 		v.EditWrite(' ')
 	case KeyBackspace, KeyBackspace2:
-		v.EditDelete(true)
+		if !v.autoPairDelete() {
+			v.EditDelete(true)
+		}
 	case KeyDelete:
 		v.EditDelete(false)
 	case KeyInsert:
@@ -53,7 +72,7 @@ func simpleEditor(v *View, key Key, ch rune, mod Modifier) {
 	case KeyArrowRight:
 		v.MoveCursor(1, 0)
 	case KeyTab:
-		v.EditWrite('\t')
+		v.EditInsertTab()
 	case KeyEsc:
 		// If not here the esc key will act like the KeySpace
 	default:
@@ -61,23 +80,398 @@ func simpleEditor(v *View, key Key, ch rune, mod Modifier) {
 	}
 }
 
-// EditWrite writes a rune at the cursor position.
+// autoPairs maps the characters paired by View.AutoPair to their closing
+// counterpart.
+var autoPairs = map[rune]rune{
+	'(': ')',
+	'[': ']',
+	'{': '}',
+	'"': '"',
+	'`': '`',
+}
+
+// autoPairWrite writes ch at the cursor position if it's paired by
+// View.AutoPair: an opening character is written with its closing one, and
+// a closing character is typed over if it's already at the cursor. It
+// returns false if ch is not handled.
+func (v *View) autoPairWrite(ch rune) bool {
+	if !v.AutoPair || v.Overwrite {
+		return false
+	}
+
+	if y := v.cy; y < v.lines.len() && v.cx < len(v.lines.line(y)) && v.lines.line(y)[v.cx].chr == ch {
+		for _, closing := range autoPairs {
+			if closing == ch {
+				v.MoveCursor(1, 0)
+				return true
+			}
+		}
+	}
+
+	closing, ok := autoPairs[ch]
+	if !ok || (v.MaxLength > 0 && v.contentLength()+2 > v.MaxLength) {
+		return false
+	}
+	v.beginEdit()
+	defer v.endEdit()
+	v.EditWrite(ch)
+	v.EditWrite(closing)
+	v.MoveCursor(-1, 0)
+	return true
+}
+
+// autoPairDelete deletes both characters of an empty pair written by
+// View.AutoPair, when the cursor is between them. It returns false if there
+// is no such pair.
+func (v *View) autoPairDelete() bool {
+	x, y := v.cx, v.cy
+	if !v.AutoPair || x <= 0 || y >= v.lines.len() || x >= len(v.lines.line(y)) {
+		return false
+	}
+	line := v.lines.line(y)
+	if closing, ok := autoPairs[line[x-1].chr]; !ok || line[x].chr != closing {
+		return false
+	}
+
+	v.beginEdit()
+	defer v.endEdit()
+	if err := v.deleteRunes(x-1, x+1, y); err == nil {
+		v.MoveCursor(-1, 0)
+	}
+	return true
+}
+
+// EditWrite writes a rune at the cursor position. If there is a selection,
+// the rune replaces the selected text.
 func (v *View) EditWrite(ch rune) {
+	v.editWrite(ch)
+}
+
+// editWrite is EditWrite, it returns false if the rune is refused because of
+// MaxLength or MaxColumn.
+func (v *View) editWrite(ch rune) bool {
+	v.beginEdit()
+	defer v.endEdit()
+
+	// Replacing a selection isn't merged with the previous typing
+	replace := v.selection != nil
+	v.DeleteSelection()
+	if v.editUnit != nil && !replace && !v.isWordDelimiter(ch) {
+		v.editUnit.coalesce = true
+	}
+	if v.combineRune(v.cx, v.cy, ch) {
+		return true
+	}
+	overwrite := v.Overwrite && v.cy < v.lines.len() && v.cx < len(v.lines.line(v.cy))
+	if v.refuseColumn(overwrite) || (!overwrite && v.refuseInsert()) {
+		return false
+	}
 	v.writeRune(v.cx, v.cy, ch)
 	v.MoveCursor(1, 0)
+	return true
+}
+
+// refuseInsert reports whether the content of the view reached MaxLength,
+// in which case OnMaxLength is called.
+func (v *View) refuseInsert() bool {
+	if v.MaxLength <= 0 || v.contentLength() < v.MaxLength {
+		return false
+	}
+	if v.OnMaxLength != nil {
+		v.OnMaxLength(v)
+	}
+	return true
+}
+
+// refuseColumn reports whether writing a rune at the cursor position would
+// go past MaxColumn, in which case OnMaxLength is called. overwrite tells if
+// the rune replaces the cell under the cursor.
+func (v *View) refuseColumn(overwrite bool) bool {
+	if v.MaxColumn <= 0 {
+		return false
+	}
+	n := 0
+	if v.cy < v.lines.len() {
+		n = len(v.lines.line(v.cy))
+	}
+	if v.cx < v.MaxColumn && (overwrite || n < v.MaxColumn) {
+		return false
+	}
+	if v.OnMaxLength != nil {
+		v.OnMaxLength(v)
+	}
+	return true
+}
+
+// contentLength returns the number of runes of the view's internal buffer,
+// line breaks included. Combining marks aren't counted.
+func (v *View) contentLength() int {
+	n := 0
+	for y := 0; y < v.lines.len(); y++ {
+		n += len(v.lines.line(y))
+	}
+	if v.lines.len() > 1 {
+		n += v.lines.len() - 1
+	}
+	return n
+}
+
+// EditWriteString writes a string at the cursor position, as if each of its
+// runes was written with EditWrite, so it replaces the selected text if there
+// is a selection. A '\n' starts a new line like
+// EditNewLine, or is replaced by a space if SingleLine is true. The whole
+// string is reverted by a single Undo. The string is truncated if it doesn't
+// fit in MaxLength.
+func (v *View) EditWriteString(s string) {
+	v.beginEdit()
+	defer v.endEdit()
+
+	for _, r := range s {
+		var ok bool
+		if r == '\n' && v.SingleLine {
+			ok = v.editWrite(' ')
+		} else if r == '\n' {
+			ok = v.editNewLine()
+		} else {
+			ok = v.editWrite(r)
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+// pasteString writes a pasted string like EditWriteString, but the new
+// lines aren't indented even if AutoIndent is true, since the pasted text
+// already has its indentation.
+func (v *View) pasteString(s string) {
+	autoIndent := v.AutoIndent
+	v.AutoIndent = false
+	defer func() { v.AutoIndent = autoIndent }()
+	v.EditWriteString(s)
+}
+
+// EditInsertTab inserts a tab at the cursor position. If ExpandTabs is true,
+// spaces up to the next tab stop are inserted instead.
+func (v *View) EditInsertTab() {
+	if !v.ExpandTabs {
+		v.EditWrite('\t')
+		return
+	}
+
+	v.beginEdit()
+	defer v.endEdit()
+
+	col := v.lineColumn(v.cx, v.cy)
+	for n := v.tabWidth() - col%v.tabWidth(); n > 0; n-- {
+		v.EditWrite(' ')
+	}
+}
+
+// EditIndentLines indents the selected lines, or the line under the cursor
+// if there is no selection, by n spaces. If n is 0 or less, the lines are
+// indented by a tab, or by TabWidth spaces if ExpandTabs is true. Empty lines
+// aren't indented. A selection that ends at the start of a line doesn't
+// include that line.
+func (v *View) EditIndentLines(n int) {
+	indent := []rune{'\t'}
+	if n > 0 || v.ExpandTabs {
+		if n <= 0 {
+			n = v.tabWidth()
+		}
+		indent = []rune(strings.Repeat(" ", n))
+	}
+
+	v.beginEdit()
+	defer v.endEdit()
+
+	if v.refuseInsert() {
+		return
+	}
+	y0, y1 := v.selectedLines()
+	for y := y0; y <= y1; y++ {
+		if len(v.lines.line(y)) > 0 {
+			v.replaceCells(0, 0, y, indent)
+		}
+	}
+}
+
+// EditDedentLines removes up to n columns of the leading whitespace of the
+// selected lines, or of the line under the cursor if there is no selection.
+// If n is 0 or less, TabWidth columns are removed. A tab that starts before
+// the n-th column is removed as a whole.
+func (v *View) EditDedentLines(n int) {
+	if n <= 0 {
+		n = v.tabWidth()
+	}
+
+	v.beginEdit()
+	defer v.endEdit()
+
+	y0, y1 := v.selectedLines()
+	for y := y0; y <= y1; y++ {
+		line := v.lines.line(y)
+		k, col := 0, 0
+		for ; k < len(line) && col < n; k++ {
+			if c := line[k].chr; c == ' ' {
+				col++
+			} else if c == '\t' {
+				col += v.tabWidth() - col%v.tabWidth()
+			} else {
+				break
+			}
+		}
+		if k > 0 {
+			v.replaceCells(0, k, y, nil)
+		}
+	}
+}
+
+// EditToggleComment comments the selected lines, or the line under the
+// cursor if there is no selection, by inserting prefix and a space after
+// their leading whitespace. If all the lines are already commented, the
+// prefix and the space that follows it are removed instead. Blank lines are
+// left as is.
+func (v *View) EditToggleComment(prefix string) {
+	if prefix == "" {
+		return
+	}
+
+	v.beginEdit()
+	defer v.endEdit()
+
+	y0, y1 := v.selectedLines()
+	p := []rune(prefix)
+	commented := true
+	for y := y0; y <= y1; y++ {
+		if !v.isBlankLine(y) && v.commentAt(y, p) < 0 {
+			commented = false
+			break
+		}
+	}
+
+	for y := y0; y <= y1; y++ {
+		if v.isBlankLine(y) {
+			continue
+		}
+		if !commented {
+			v.replaceCells(v.indentLen(y), 0, y, append(p, ' '))
+			continue
+		}
+		x := v.commentAt(y, p)
+		n := len(p)
+		if line := v.lines.line(y); x+n < len(line) && line[x+n].chr == ' ' {
+			n++
+		}
+		v.replaceCells(x, n, y, nil)
+	}
+}
+
+// commentAt returns the position of prefix in the line y if the line starts
+// with it after its leading whitespace, or -1 otherwise.
+func (v *View) commentAt(y int, prefix []rune) int {
+	x := v.indentLen(y)
+	line := v.lines.line(y)
+	if len(line)-x < len(prefix) {
+		return -1
+	}
+	for i, r := range prefix {
+		if line[x+i].chr != r {
+			return -1
+		}
+	}
+	return x
+}
+
+// indentLen returns the number of whitespace cells that start the line y.
+func (v *View) indentLen(y int) int {
+	line := v.lines.line(y)
+	n := 0
+	for n < len(line) && (line[n].chr == ' ' || line[n].chr == '\t') {
+		n++
+	}
+	return n
 }
 
 // EditDeleteToStartOfLine is the equivalent of pressing ctrl+U in your terminal, it deletes to the start of the line. Or if you are already at the start of the line, it deletes the newline character
 func (v *View) EditDeleteToStartOfLine() {
-	x, _ := v.Cursor()
-	if x == 0 {
+	v.beginEdit()
+	defer v.endEdit()
+
+	x, y := v.cx, v.cy
+	if y >= v.lines.len() || x == 0 {
+		if y > 0 && y < v.lines.len() {
+			v.pushKill("\n")
+		}
 		v.EditDelete(true)
-	} else {
-		// delete characters until we are the start of the line
-		for x > 0 {
-			v.EditDelete(true)
-			x, _ = v.Cursor()
+		return
+	}
+
+	// delete characters until we are the start of the line
+	if x > len(v.lines.line(y)) {
+		x = len(v.lines.line(y))
+	}
+	v.pushKill(cellsText(v.lines.line(y)[:x]))
+	if err := v.deleteRunes(0, x, y); err == nil {
+		v.MoveCursor(-v.cx, 0)
+	}
+}
+
+// EditDeleteToEndOfLine is the equivalent of pressing ctrl+K in your terminal, it deletes to the end of the line. Or if you are already at the end of the line, it deletes the newline character
+func (v *View) EditDeleteToEndOfLine() {
+	v.beginEdit()
+	defer v.endEdit()
+
+	x, y := v.cx, v.cy
+	if y >= v.lines.len() {
+		return
+	}
+
+	line := v.lines.line(y)
+	if x >= len(line) {
+		if y+1 < v.lines.len() {
+			v.pushKill("\n")
 		}
+		_ = v.mergeLines(y)
+		return
+	}
+	v.pushKill(cellsText(line[x:]))
+	_ = v.deleteRunes(x, len(line), y)
+}
+
+// EditDeleteWord is the equivalent of pressing ctrl+W in your terminal, it
+// deletes the word before the cursor. Whitespace and punctuation directly
+// before the cursor are skipped first, so repeated calls on `foo.bar` remove
+// `bar` and then `foo.`. If you are already at the start of the line, it
+// deletes the newline character.
+func (v *View) EditDeleteWord() {
+	v.beginEdit()
+	defer v.endEdit()
+
+	x, y := v.cx, v.cy
+	if y >= v.lines.len() || x == 0 {
+		if y > 0 && y < v.lines.len() {
+			v.pushKill("\n")
+		}
+		v.EditDelete(true)
+		return
+	}
+
+	line := v.lines.line(y)
+	if x > len(line) {
+		x = len(line)
+	}
+	start := x
+	for start > 0 && v.isWordDelimiter(line[start-1].chr) {
+		start--
+	}
+	for start > 0 && !v.isWordDelimiter(line[start-1].chr) {
+		start--
+	}
+
+	v.pushKill(cellsText(line[start:x]))
+	if err := v.deleteRunes(start, x, y); err == nil {
+		v.MoveCursor(start-v.cx, 0)
 	}
 }
 
@@ -112,11 +506,14 @@ func (v *View) EditGotoToEndOfLine() {
 // EditDelete deletes a rune at the cursor position. back determines the
 // direction.
 func (v *View) EditDelete(back bool) {
+	v.beginEdit()
+	defer v.endEdit()
+
 	x, y := v.cx, v.cy
 	if y < 0 {
 		return
 	}
-	if y >= len(v.lines) {
+	if y >= v.lines.len() {
 		v.MoveCursor(-1, 0)
 		return
 	}
@@ -138,46 +535,360 @@ func (v *View) EditDelete(back bool) {
 		}
 		return
 	}
-	if x == len(v.lines[y]) { // end of the line
+	if x == len(v.lines.line(y)) { // end of the line
 		_ = v.mergeLines(y)
 		return
 	}
 	v.deleteRune(v.cx, v.cy) // start/middle of the line
 }
 
-// EditNewLine inserts a new line under the cursor.
+// EditUpcaseWord converts the word at the cursor to upper case, like M-u in
+// Emacs. Only the part of the word after the cursor is converted, the
+// whitespace and punctuation before the word are skipped. The cursor is moved
+// to the end of the word.
+func (v *View) EditUpcaseWord() {
+	v.editWord(func(r rune, first bool) rune {
+		return unicode.ToUpper(r)
+	})
+}
+
+// EditDowncaseWord converts the word at the cursor to lower case, like M-l
+// in Emacs. See EditUpcaseWord.
+func (v *View) EditDowncaseWord() {
+	v.editWord(func(r rune, first bool) rune {
+		return unicode.ToLower(r)
+	})
+}
+
+// EditCapitalizeWord capitalizes the word at the cursor, like M-c in Emacs.
+// See EditUpcaseWord.
+func (v *View) EditCapitalizeWord() {
+	v.editWord(func(r rune, first bool) rune {
+		if first {
+			return unicode.ToTitle(r)
+		}
+		return unicode.ToLower(r)
+	})
+}
+
+// editWord replaces the runes of the word at the cursor by the result of f,
+// first is true for the first converted rune.
+func (v *View) editWord(f func(r rune, first bool) rune) {
+	v.beginEdit()
+	defer v.endEdit()
+
+	if v.lines.len() == 0 {
+		return
+	}
+	x, y := v.clipPoint(v.cx, v.cy)
+
+	line := v.lines.line(y)
+	for x < len(line) && v.isWordDelimiter(line[x].chr) {
+		x++
+	}
+	start := x
+	for x < len(line) && !v.isWordDelimiter(line[x].chr) {
+		x++
+	}
+	if x > start {
+		v.tainted = true
+		v.recordChange(y, 1, 1)
+		for i := start; i < x; i++ {
+			line[i].chr = f(line[i].chr, i == start)
+		}
+	}
+	v.placeCursor(x, y)
+}
+
+// EditTransposeChars is the equivalent of pressing ctrl+T in your terminal,
+// it swaps the character before the cursor with the one at the cursor and
+// moves the cursor forward. At the end of the line the two characters before
+// the cursor are swapped. It does nothing at the start of a line.
+func (v *View) EditTransposeChars() {
+	v.beginEdit()
+	defer v.endEdit()
+
+	x, y := v.cx, v.cy
+	if y >= v.lines.len() || x <= 0 {
+		return
+	}
+
+	line := v.lines.line(y)
+	atEnd := x >= len(line)
+	if atEnd {
+		x = len(line) - 1
+	}
+	if x <= 0 {
+		return
+	}
+
+	v.tainted = true
+	v.recordChange(y, 1, 1)
+	line[x-1], line[x] = line[x], line[x-1]
+	if !atEnd {
+		v.MoveCursor(1, 0)
+	}
+}
+
+// EditDuplicateLine inserts a copy of the line under the cursor below it.
+// The cells are copied with their colors and the cursor is not moved.
+func (v *View) EditDuplicateLine() {
+	v.beginEdit()
+	defer v.endEdit()
+
+	y := v.cy
+	if y >= v.lines.len() {
+		return
+	}
+	_ = v.insertLine(y+1, append([]cell{}, v.lines.line(y)...))
+}
+
+// EditDeleteLine deletes the line under the cursor, like dd in Vim, and adds
+// it to the kill ring so it can be put back with EditYank. The cursor is
+// moved to the start of the next line, or of the new last line if the last
+// line was deleted. Deleting the only line leaves the buffer empty.
+func (v *View) EditDeleteLine() {
+	v.beginEdit()
+	defer v.endEdit()
+
+	y := v.cy
+	if y >= v.lines.len() {
+		return
+	}
+	v.pushKill(cellsText(v.lines.line(y)) + "\n")
+	if err := v.deleteLine(y); err != nil {
+		return
+	}
+	if y >= v.lines.len() {
+		y = v.lines.len() - 1
+	}
+	if y < 0 {
+		y = 0
+	}
+	v.ox = 0
+	v.placeCursor(0, y)
+}
+
+// EditMoveLineUp swaps the line under the cursor with the previous one, the
+// cursor follows the moved line. It does nothing on the first line.
+func (v *View) EditMoveLineUp() {
+	v.beginEdit()
+	defer v.endEdit()
+
+	y := v.cy
+	if y <= 0 || y >= v.lines.len() {
+		return
+	}
+	if err := v.swapLines(y - 1); err == nil {
+		v.placeCursor(v.cx, y-1)
+	}
+}
+
+// EditMoveLineDown swaps the line under the cursor with the next one, the
+// cursor follows the moved line. It does nothing on the last line.
+func (v *View) EditMoveLineDown() {
+	v.beginEdit()
+	defer v.endEdit()
+
+	y := v.cy
+	if y+1 >= v.lines.len() {
+		return
+	}
+	if err := v.swapLines(y); err == nil {
+		v.placeCursor(v.cx, y+1)
+	}
+}
+
+// EditJoinLines joins the next line to the line under the cursor, like J in
+// Vim. The leading whitespace of the next line is replaced by a single space,
+// which is omitted if one of the lines is empty or if the current line ends
+// with whitespace. The cursor is moved to the join point. It does nothing on
+// the last line.
+func (v *View) EditJoinLines() {
+	v.beginEdit()
+	defer v.endEdit()
+
+	y := v.cy
+	if y+1 >= v.lines.len() {
+		return
+	}
+
+	next := v.lines.line(y + 1)
+	n := 0
+	for n < len(next) && isSpaceCell(next[n]) {
+		n++
+	}
+	if n > 0 {
+		_ = v.deleteRunes(0, n, y+1)
+	}
+
+	x := len(v.lines.line(y))
+	if x > 0 && len(v.lines.line(y+1)) > 0 && !isSpaceCell(v.lines.line(y)[x-1]) {
+		_ = v.writeRune(x, y, ' ')
+	}
+	_ = v.mergeLines(y)
+	v.placeCursor(x, y)
+}
+
+// EditClearToBufferEnd deletes the text from the cursor to the end of the
+// buffer, the cursor is not moved. Clearing from the start of the buffer
+// leaves it empty.
+func (v *View) EditClearToBufferEnd() {
+	v.beginEdit()
+	defer v.endEdit()
+
+	x, y := v.cx, v.cy
+	if y >= v.lines.len() {
+		return
+	}
+	if x > len(v.lines.line(y)) {
+		x = len(v.lines.line(y))
+	}
+
+	last := v.lines.len() - 1
+	_ = v.deleteText(x, y, len(v.lines.line(last)), last)
+	if v.lines.len() == 1 && len(v.lines.line(0)) == 0 {
+		_ = v.deleteLine(0)
+	}
+}
+
+// EditNewLine inserts a new line under the cursor, replacing the selected
+// text if there is a selection. If AutoIndent is true, the new line starts
+// with the whitespace of the current line that is before the cursor. If
+// SingleLine is true, it calls OnSubmit instead.
 func (v *View) EditNewLine() {
-	v.breakLine(v.cx, v.cy)
+	if v.SingleLine {
+		if v.OnSubmit != nil {
+			v.OnSubmit(v)
+		}
+		return
+	}
+	v.editNewLine()
+}
+
+// editNewLine is EditNewLine, it returns false if the line break is refused
+// because of MaxLength.
+func (v *View) editNewLine() bool {
+	v.beginEdit()
+	defer v.endEdit()
+
+	v.DeleteSelection()
+	if v.refuseInsert() {
+		return false
+	}
+
+	var indent []cell
+	if y := v.cy; v.AutoIndent && y < v.lines.len() {
+		line := v.lines.line(y)
+		n := 0
+		for n < v.cx && n < len(line) && (line[n].chr == ' ' || line[n].chr == '\t') {
+			n++
+		}
+		indent = append(indent, line[:n]...)
+	}
+
+	if err := v.breakLine(v.cx, v.cy); err == nil && len(indent) > 0 {
+		v.recordChange(v.cy+1, 1, 1)
+		v.lines.set(v.cy+1, append(indent, v.lines.line(v.cy+1)...))
+	}
+	v.ox = 0
+	v.placeCursor(len(indent), v.cy+1)
+	return true
+}
+
+// EditOpenLineBelow inserts an empty line below the line under the cursor
+// and moves the cursor to it, like o in Vim. Contrary to EditNewLine, the
+// current line isn't split. If AutoIndent is true, the new line starts with
+// the whitespace that starts the current line. It does nothing if SingleLine
+// is true.
+func (v *View) EditOpenLineBelow() {
+	v.openLine(1)
+}
+
+// EditOpenLineAbove inserts an empty line above the line under the cursor
+// and moves the cursor to it, like O in Vim. It works like
+// EditOpenLineBelow.
+func (v *View) EditOpenLineAbove() {
+	v.openLine(0)
+}
+
+// openLine inserts an empty line before the line cy+dy and moves the cursor
+// to it.
+func (v *View) openLine(dy int) {
+	v.beginEdit()
+	defer v.endEdit()
+
+	if v.SingleLine || v.refuseInsert() {
+		return
+	}
+	if v.lines.len() == 0 {
+		_ = v.insertLine(0, nil)
+	}
+	y := v.cy
+	if y >= v.lines.len() {
+		y = v.lines.len() - 1
+	}
+
+	var indent []cell
+	if v.AutoIndent {
+		line := v.lines.line(y)
+		n := 0
+		for n < len(line) && (line[n].chr == ' ' || line[n].chr == '\t') {
+			n++
+		}
+		indent = append(indent, line[:n]...)
+	}
+
+	if err := v.insertLine(y+dy, indent); err != nil {
+		return
+	}
 	v.ox = 0
-	v.cy = v.cy + 1
-	v.cx = 0
+	v.placeCursor(len(indent), y+dy)
 }
 
-// MoveCursor moves the cursor relative from it's current possition
+// MoveCursor moves the cursor relative from it's current possition.
+// Consecutive vertical moves keep the cursor at the column it started from
+// when the lines are long enough, like in most editors.
 func (v *View) MoveCursor(dx, dy int) {
+	if v.SingleLine {
+		dy = 0
+	}
 	newX, newY := v.cx+dx, v.cy+dy
 
-	if len(v.lines) == 0 {
-		v.cx, v.cy = 0, 0
+	// Vertical moves go to the goal column, the column the cursor was at
+	// before the first of consecutive vertical moves
+	goal := -1
+	if dx == 0 && dy != 0 {
+		goal = v.lineColumn(v.cx, v.cy)
+		if v.hasGoal {
+			goal = v.goalColumn
+		}
+	}
+
+	if v.lines.len() == 0 {
+		v.setCursor(0, 0)
 		return
 	}
 
 	// If newY is more than all lines set it to the last line
-	if newY >= len(v.lines) {
-		newY = len(v.lines) - 1
+	if newY >= v.lines.len() {
+		newY = v.lines.len() - 1
 	}
 	if newY < 0 {
 		newY = 0
 	}
 
-	line := v.lines[newY]
+	line := v.lines.line(newY)
+	if goal >= 0 {
+		newX = v.columnCell(line, goal)
+	}
 
 	// If newX is more than the line width go to the next line if possible
 	// Otherwhise do nothing
 	if newX > len(line) {
-		if dy == 0 && newY+1 < len(v.lines) {
+		if dy == 0 && newY+1 < v.lines.len() {
 			newY++
-			// line = v.lines[newY] // Uncomment if adding code that uses line
+			// line = v.lines.line(newY) // Uncomment if adding code that uses line
 			newX = 0
 		} else {
 			newX = len(line)
@@ -188,44 +899,367 @@ func (v *View) MoveCursor(dx, dy int) {
 	if newX < 0 {
 		if newY > 0 {
 			newY--
-			line = v.lines[newY]
+			line = v.lines.line(newY)
 			newX = len(line)
 		} else {
 			newX = 0
 		}
 	}
 
+	v.placeCursor(newX, newY)
+	if goal >= 0 {
+		v.goalColumn, v.hasGoal = goal, true
+	}
+}
+
+// MoveCursorWordLeft moves the cursor to the start of the current word, or
+// the previous one if the cursor is already at the start of a word. If the
+// cursor is at the start of a line it moves to the end of the previous line.
+// Words are separated by the runes WordDelimiter reports, by default
+// punctuation and symbols too; set it to unicode.IsSpace to only stop at
+// whitespace.
+func (v *View) MoveCursorWordLeft() {
+	if v.lines.len() == 0 {
+		v.placeCursor(0, 0)
+		return
+	}
+	x, y := v.clipPoint(v.cx, v.cy)
+
+	if x == 0 {
+		if y > 0 {
+			v.placeCursor(len(v.lines.line(y-1)), y-1)
+		}
+		return
+	}
+
+	line := v.lines.line(y)
+	for x > 0 && v.isWordDelimiter(line[x-1].chr) {
+		x--
+	}
+	for x > 0 && !v.isWordDelimiter(line[x-1].chr) {
+		x--
+	}
+	v.placeCursor(x, y)
+}
+
+// MoveCursorWordRight moves the cursor to the start of the next word, or the
+// end of the line if there is no word left. If the cursor is at the end of a
+// line it moves to the start of the next line. Words are separated like in
+// MoveCursorWordLeft.
+func (v *View) MoveCursorWordRight() {
+	if v.lines.len() == 0 {
+		v.placeCursor(0, 0)
+		return
+	}
+	x, y := v.clipPoint(v.cx, v.cy)
+
+	line := v.lines.line(y)
+	if x == len(line) {
+		if y+1 < v.lines.len() {
+			v.placeCursor(0, y+1)
+		}
+		return
+	}
+
+	for x < len(line) && !v.isWordDelimiter(line[x].chr) {
+		x++
+	}
+	for x < len(line) && v.isWordDelimiter(line[x].chr) {
+		x++
+	}
+	v.placeCursor(x, y)
+}
+
+// MoveCursorToLineStart moves the cursor to the start of the current line.
+// Contrary to EditGotoToStartOfLine, the view is only scrolled if the start
+// of the line isn't visible.
+func (v *View) MoveCursorToLineStart() {
+	_, y := v.clipPoint(v.cx, v.cy)
+	v.placeCursor(0, y)
+}
+
+// MoveCursorToLineEnd moves the cursor after the last cell of the current
+// line. Contrary to EditGotoToEndOfLine, it doesn't move through the line and
+// the view is only scrolled if the end of the line isn't visible.
+func (v *View) MoveCursorToLineEnd() {
+	if v.lines.len() == 0 {
+		v.placeCursor(0, 0)
+		return
+	}
+	_, y := v.clipPoint(v.cx, v.cy)
+	v.placeCursor(len(v.lines.line(y)), y)
+}
+
+// MoveCursorToFirstNonBlank moves the cursor to the first cell of the current
+// line that isn't a space or a tab, like ^ in Vim, or to the end of the line
+// if it's blank.
+func (v *View) MoveCursorToFirstNonBlank() {
+	if v.lines.len() == 0 {
+		v.placeCursor(0, 0)
+		return
+	}
+	_, y := v.clipPoint(v.cx, v.cy)
+
+	line, x := v.lines.line(y), 0
+	for x < len(line) && (line[x].chr == ' ' || line[x].chr == '\t') {
+		x++
+	}
+	v.placeCursor(x, y)
+}
+
+// MoveCursorToBufferStart moves the cursor to the start of the buffer.
+func (v *View) MoveCursorToBufferStart() {
+	v.placeCursor(0, 0)
+}
+
+// MoveCursorToBufferEnd moves the cursor to the end of the last line of the
+// buffer.
+func (v *View) MoveCursorToBufferEnd() {
+	if v.lines.len() == 0 {
+		v.placeCursor(0, 0)
+		return
+	}
+	y := v.lines.len() - 1
+	v.placeCursor(len(v.lines.line(y)), y)
+}
+
+// MoveCursorParagraphDown moves the cursor to the blank line after the current
+// paragraph, or the end of the buffer if it's the last paragraph. A line is
+// blank if it only contains whitespace.
+func (v *View) MoveCursorParagraphDown() {
+	y := v.cy
+	for y < v.lines.len() && v.isBlankLine(y) {
+		y++
+	}
+	for y < v.lines.len() && !v.isBlankLine(y) {
+		y++
+	}
+
+	if y >= v.lines.len() {
+		v.MoveCursorToBufferEnd()
+		return
+	}
+	v.placeCursor(0, y)
+}
+
+// MoveCursorParagraphUp moves the cursor to the blank line before the current
+// paragraph, or the start of the buffer if it's the first paragraph. A line is
+// blank if it only contains whitespace.
+func (v *View) MoveCursorParagraphUp() {
+	y := v.cy
+	if y >= v.lines.len() {
+		y = v.lines.len() - 1
+	}
+	for y >= 0 && v.isBlankLine(y) {
+		y--
+	}
+	for y >= 0 && !v.isBlankLine(y) {
+		y--
+	}
+
+	if y < 0 {
+		y = 0
+	}
+	v.placeCursor(0, y)
+}
+
+// ReflowParagraph rewraps the paragraph under the cursor so its lines are
+// at most width columns wide, like gq in Vim. Words are separated by
+// whitespace and are never split, the indentation of the first line is kept
+// on every line. The cursor is moved to the start of the paragraph. It does
+// nothing if the cursor is on a blank line.
+func (v *View) ReflowParagraph(width int) {
+	v.beginEdit()
+	defer v.endEdit()
+
+	y := v.cy
+	if width <= 0 || y >= v.lines.len() || v.isBlankLine(y) {
+		return
+	}
+	start, end := y, y+1
+	for start > 0 && !v.isBlankLine(start-1) {
+		start--
+	}
+	for end < v.lines.len() && !v.isBlankLine(end) {
+		end++
+	}
+
+	first := v.lines.line(start)
+	n := 0
+	for n < len(first) && isSpaceCell(first[n]) {
+		n++
+	}
+	indent := first[:n]
+
+	var words [][]cell
+	for _, line := range v.lines.slice(start, end) {
+		for x := 0; x < len(line); {
+			for x < len(line) && isSpaceCell(line[x]) {
+				x++
+			}
+			wordStart := x
+			for x < len(line) && !isSpaceCell(line[x]) {
+				x++
+			}
+			if x > wordStart {
+				words = append(words, line[wordStart:x])
+			}
+		}
+	}
+
+	var lines [][]cell
+	var cur []cell
+	for _, word := range words {
+		if cur != nil {
+			next := append(append(append([]cell{}, cur...), cell{chr: ' ', fgColor: v.FgColor, bgColor: v.BgColor}), word...)
+			if v.lineWidth(next) <= width {
+				cur = next
+				continue
+			}
+			lines = append(lines, cur)
+		}
+		cur = append(append([]cell{}, indent...), word...)
+	}
+	lines = append(lines, cur)
+
+	v.recordChange(start, end-start, len(lines))
+	v.replaceLines(start, end-start, lines)
+	v.placeCursor(0, start)
+}
+
+// isBlankLine reports whether the line y of the buffer only contains
+// whitespace.
+func (v *View) isBlankLine(y int) bool {
+	for _, c := range v.lines.line(y) {
+		if c.chr != 0 && !unicode.IsSpace(c.chr) {
+			return false
+		}
+	}
+	return true
+}
+
+// clipPoint returns the nearest position of the view's internal buffer to
+// the point (x, y).
+func (v *View) clipPoint(x, y int) (int, int) {
+	if v.lines.len() == 0 {
+		return 0, 0
+	}
+	if y >= v.lines.len() {
+		y = v.lines.len() - 1
+	}
+	if y < 0 {
+		y = 0
+	}
+	if x > len(v.lines.line(y)) {
+		x = len(v.lines.line(y))
+	}
+	if x < 0 {
+		x = 0
+	}
+	return x, y
+}
+
+// placeCursor sets the cursor to the given buffer position and adjusts the
+// view offsets so the cursor stays visible.
+func (v *View) placeCursor(newX, newY int) {
 	maxX, maxY := v.Size()
 	newXOnScreen, newYOnScreen, _ := v.linesPosOnScreen(newX, newY)
 
-	// Set the view offset
-	if newYOnScreen > v.oy+maxY-1 {
-		v.oy = newYOnScreen - maxY + 1
+	// Set the view offset, keeping ScrollMargin rows visible around the
+	// cursor unless it's near the start or the end of the buffer
+	top, bottom := newYOnScreen, newYOnScreen
+	if m := v.scrollMargin(maxY); m > 0 {
+		top, bottom = newYOnScreen-m, newYOnScreen+m
+		if top < 0 {
+			top = 0
+		}
+		if last := len(v.viewLines()) - 1; bottom > last {
+			bottom = last
+		}
+		if bottom < newYOnScreen {
+			bottom = newYOnScreen
+		}
+	}
+	if bottom > v.oy+maxY-1 {
+		v.oy = bottom - maxY + 1
 	}
-	if newYOnScreen < v.oy {
-		v.oy = newYOnScreen
+	if top < v.oy {
+		v.oy = top
 	}
 
 	if !v.Wrap {
-		if newXOnScreen > v.ox+maxX-1 {
-			v.ox = newXOnScreen - maxX + 1
+		// A wide character under the cursor must be fully visible
+		lastX := newXOnScreen
+		if newY < v.lines.len() && newX < len(v.lines.line(newY)) {
+			if c := v.lines.line(newY)[newX]; c.chr != '\t' {
+				if w := v.cellWidth(c, newXOnScreen); w > 1 && w <= maxX {
+					lastX += w - 1
+				}
+			}
 		}
-		// Size of the line preview when moving to the left edge.
-		// This should help to display hidden text of the line when
-		// wrapping is off and moving towards beginning of the line.
-		// 2 is currently set as the max length of characters which are visible.
-		prevSize := 0 // this is default, no preview (used when hitting the beginnig)
-		if maxX > 2 && newXOnScreen-2 >= 0 {
-			prevSize = 2
-		} else if maxX > 1 && newXOnScreen-1 >= 0 {
-			prevSize = 1
+		// Keep SideScrollMargin columns visible around the cursor, unless
+		// it's near the start or the end of the line
+		left, right := newXOnScreen-leftPreview(maxX), lastX
+		if left < 0 {
+			left = 0
 		}
-		if newXOnScreen-prevSize < v.ox {
-			v.ox = newXOnScreen - prevSize
+		if m := v.sideScrollMargin(maxX); m > 0 {
+			left, right = newXOnScreen-m, lastX+m
+			if left < 0 {
+				left = 0
+			}
+			width := 0
+			if newY < v.lines.len() {
+				width = v.lineWidth(v.lines.line(newY))
+			}
+			if right > width {
+				right = width
+			}
+			if right < lastX {
+				right = lastX
+			}
+		}
+		if right > v.ox+maxX-1 {
+			v.ox = right - maxX + 1
+		}
+		if left < v.ox {
+			v.ox = left
 		}
 	}
 
-	v.cx, v.cy = newX, newY
+	v.setCursor(newX, newY)
+}
+
+// scrollMargin returns ScrollMargin, reduced so the margins above and below
+// the cursor fit in a view of the given height.
+func (v *View) scrollMargin(height int) int {
+	m := v.ScrollMargin
+	if max := (height - 1) / 2; m > max {
+		m = max
+	}
+	return m
+}
+
+// sideScrollMargin returns SideScrollMargin, reduced so the margins on the
+// left and the right of the cursor fit in a view of the given width.
+func (v *View) sideScrollMargin(width int) int {
+	m := v.SideScrollMargin
+	if max := (width - 1) / 2; m > max {
+		m = max
+	}
+	return m
+}
+
+// leftPreview returns the number of columns kept visible on the left of the
+// cursor without SideScrollMargin, at most 2 if the view is wide enough.
+func leftPreview(width int) int {
+	switch {
+	case width > 2:
+		return 2
+	case width > 1:
+		return 1
+	}
+	return 0
 }
 
 // writeRune writes a rune into the view's internal buffer, at the
@@ -239,12 +1273,14 @@ func (v *View) writeRune(x, y int, ch rune) error {
 		return errors.New("invalid point")
 	}
 
-	if y >= len(v.lines) {
-		newLines := make([][]cell, y-len(v.lines)+1)
-		v.lines = append(v.lines, newLines...)
+	if y >= v.lines.len() {
+		newLines := make([][]cell, y-v.lines.len()+1)
+		v.recordChange(v.lines.len(), 0, len(newLines))
+		v.lines.append(newLines...)
 	}
+	v.recordChange(y, 1, 1)
 
-	line := v.lines[y]
+	line := v.lines.line(y)
 	lineLen := len(line)
 
 	var toInsert []cell
@@ -253,13 +1289,13 @@ func (v *View) writeRune(x, y int, ch rune) error {
 	} else if !v.Overwrite {
 		toInsert = make([]cell, 1)
 	}
-	v.lines[y] = append(v.lines[y], toInsert...)
+	v.lines.set(y, append(v.lines.line(y), toInsert...))
 
 	if !v.Overwrite || (v.Overwrite && x+1 >= lineLen) {
-		copy(v.lines[y][x+1:], v.lines[y][x:])
+		copy(v.lines.line(y)[x+1:], v.lines.line(y)[x:])
 	}
 
-	v.lines[y][x] = cell{
+	v.lines.line(y)[x] = cell{
 		fgColor: v.FgColor,
 		bgColor: v.BgColor,
 		chr:     ch,
@@ -268,32 +1304,189 @@ func (v *View) writeRune(x, y int, ch rune) error {
 	return nil
 }
 
+// combineRune attaches ch to the cell before the point (x, y) of the view's
+// internal buffer if ch is a combining mark, so the cell and its marks are
+// drawn and edited as a single character. It returns false if ch is not a
+// combining mark or if there is no cell before the point.
+func (v *View) combineRune(x, y int, ch rune) bool {
+	if !isCombining(ch) || x <= 0 || y < 0 || y >= v.lines.len() || x > len(v.lines.line(y)) {
+		return false
+	}
+	v.tainted = true
+	v.recordChange(y, 1, 1)
+
+	c := &v.lines.line(y)[x-1]
+	c.combining = append(append([]rune{}, c.combining...), ch)
+	return true
+}
+
+// isCombining reports whether r is a combining mark.
+func isCombining(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me)
+}
+
 // deleteRune removes a rune from the view's internal buffer, at the
 // position corresponding to the point (x, y).
 // returns error if invalid point is specified.
 func (v *View) deleteRune(x, y int) error {
 	v.tainted = true
 
-	if x < 0 || y < 0 || y >= len(v.lines) || x >= len(v.lines[y]) {
+	if x < 0 || y < 0 || y >= v.lines.len() || x >= len(v.lines.line(y)) {
+		return errors.New("invalid point")
+	}
+
+	v.recordChange(y, 1, 1)
+	v.lines.set(y, append(v.lines.line(y)[:x], v.lines.line(y)[x+1:]...))
+	return nil
+}
+
+// deleteRunes removes the runes in the range [x0, x1) from the line y of the
+// view's internal buffer.
+// returns error if invalid range is specified.
+func (v *View) deleteRunes(x0, x1, y int) error {
+	v.tainted = true
+
+	if x0 < 0 || x1 < x0 || y < 0 || y >= v.lines.len() || x1 > len(v.lines.line(y)) {
 		return errors.New("invalid point")
 	}
 
-	v.lines[y] = append(v.lines[y][:x], v.lines[y][x+1:]...)
+	v.recordChange(y, 1, 1)
+	v.lines.set(y, append(v.lines.line(y)[:x0], v.lines.line(y)[x1:]...))
 	return nil
 }
 
+// replaceCells replaces the n cells of the line y that start at x by cells
+// holding runes, using the view's colors. The cursor and the selection are
+// moved so they stay on the same text, the points inside the replaced cells
+// are moved to x. The start of a selection at the start of a line isn't
+// moved, so the line stays entirely selected.
+func (v *View) replaceCells(x, n, y int, runes []rune) {
+	v.tainted = true
+	v.recordChange(y, 1, 1)
+
+	line := v.lines.line(y)
+	cells := make([]cell, 0, len(line)-n+len(runes))
+	cells = append(cells, line[:x]...)
+	for _, r := range runes {
+		cells = append(cells, cell{fgColor: v.FgColor, bgColor: v.BgColor, chr: r})
+	}
+	v.lines.set(y, append(cells, line[x+n:]...))
+
+	shift := func(px int) int {
+		switch {
+		case px < x:
+			return px
+		case px < x+n:
+			return x
+		}
+		return px + len(runes) - n
+	}
+	if v.cy == y {
+		v.setCursor(shift(v.cx), y)
+	}
+	if s := v.selection; s != nil && !s.block {
+		if s.y0 == y && s.x0 > 0 {
+			s.x0 = shift(s.x0)
+		}
+		if s.y1 == y {
+			s.x1 = shift(s.x1)
+		}
+	}
+}
+
+// deleteLine removes the line y from the view's internal buffer.
+// returns error if invalid point is specified.
+func (v *View) deleteLine(y int) error {
+	v.tainted = true
+
+	if y < 0 || y >= v.lines.len() {
+		return errors.New("invalid point")
+	}
+
+	v.recordChange(y, 1, 0)
+	v.lines.remove(y, 1)
+	return nil
+}
+
+// deleteText removes the text between the points (x0, y0) and (x1, y1) of
+// the view's internal buffer, the cell at (x1, y1) is kept.
+// returns error if invalid range is specified.
+func (v *View) deleteText(x0, y0, x1, y1 int) error {
+	v.tainted = true
+
+	if y0 < 0 || y1 < y0 || y1 >= v.lines.len() || (y0 == y1 && x1 < x0) ||
+		x0 < 0 || x0 > len(v.lines.line(y0)) || x1 < 0 || x1 > len(v.lines.line(y1)) {
+		return errors.New("invalid point")
+	}
+
+	v.recordChange(y0, y1-y0+1, 1)
+	line := append(append([]cell{}, v.lines.line(y0)[:x0]...), v.lines.line(y1)[x1:]...)
+	v.lines.remove(y0+1, y1-y0)
+	v.lines.set(y0, line)
+	return nil
+}
+
+// DefaultWordDelimiter is the default value of View.WordDelimiter, like in
+// readline whitespace, punctuation and symbols separate words.
+func DefaultWordDelimiter(r rune) bool {
+	return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// isWordDelimiter reports whether r separates words for the word-wise
+// editing helpers. NUL cells are treated like spaces.
+func (v *View) isWordDelimiter(r rune) bool {
+	if r == 0 {
+		return true
+	}
+	if v.WordDelimiter == nil {
+		return DefaultWordDelimiter(r)
+	}
+	return v.WordDelimiter(r)
+}
+
 // mergeLines merges the lines "y" and "y+1" if possible.
 func (v *View) mergeLines(y int) error {
 	v.tainted = true
 
-	if y < 0 || y >= len(v.lines) {
+	if y < 0 || y >= v.lines.len() {
+		return errors.New("invalid point")
+	}
+
+	if y+1 < v.lines.len() { // If we are already on the last line this would panic
+		v.recordChange(y, 2, 1)
+		v.lines.set(y, append(v.lines.line(y), v.lines.line(y+1)...))
+		v.lines.remove(y+1, 1)
+	}
+	return nil
+}
+
+// insertLine inserts a line in the view's internal buffer before the line y,
+// y can be the number of lines to append the line.
+// returns error if invalid point is specified.
+func (v *View) insertLine(y int, line []cell) error {
+	v.tainted = true
+
+	if y < 0 || y > v.lines.len() {
 		return errors.New("invalid point")
 	}
 
-	if y+1 < len(v.lines) { // If we are already on the last line this would panic
-		v.lines[y] = append(v.lines[y], v.lines[y+1]...)
-		v.lines = append(v.lines[:y+1], v.lines[y+2:]...)
+	v.recordChange(y, 0, 1)
+	v.lines.insert(y, line)
+	return nil
+}
+
+// swapLines swaps the lines "y" and "y+1" if possible.
+func (v *View) swapLines(y int) error {
+	v.tainted = true
+
+	if y < 0 || y+1 >= v.lines.len() {
+		return errors.New("invalid point")
 	}
+
+	v.recordChange(y, 2, 2)
+	line := v.lines.line(y)
+	v.lines.set(y, v.lines.line(y+1))
+	v.lines.set(y+1, line)
 	return nil
 }
 
@@ -302,25 +1495,23 @@ func (v *View) mergeLines(y int) error {
 func (v *View) breakLine(x, y int) error {
 	v.tainted = true
 
-	if y < 0 || y >= len(v.lines) {
+	if y < 0 || y >= v.lines.len() {
 		return errors.New("invalid point")
 	}
 
+	v.recordChange(y, 1, 2)
+
 	var left, right []cell
-	if x < len(v.lines[y]) { // break line
-		left = make([]cell, len(v.lines[y][:x]))
-		copy(left, v.lines[y][:x])
-		right = make([]cell, len(v.lines[y][x:]))
-		copy(right, v.lines[y][x:])
+	if x < len(v.lines.line(y)) { // break line
+		left = make([]cell, len(v.lines.line(y)[:x]))
+		copy(left, v.lines.line(y)[:x])
+		right = make([]cell, len(v.lines.line(y)[x:]))
+		copy(right, v.lines.line(y)[x:])
 	} else { // new empty line
-		left = v.lines[y]
+		left = v.lines.line(y)
 	}
 
-	lines := make([][]cell, len(v.lines)+1)
-	lines[y] = left
-	lines[y+1] = right
-	copy(lines, v.lines[:y])
-	copy(lines[y+2:], v.lines[y+1:])
-	v.lines = lines
+	v.lines.set(y, left)
+	v.lines.insert(y+1, right)
 	return nil
 }
@@ -0,0 +1,1249 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"reflect"
+	"testing"
+	"unicode"
+)
+
+// newTestView creates a view with a content area of width x height cells
+// that is filled with the given lines. It doesn't need a screen.
+func newTestView(width, height int, lines ...string) *View {
+	g := &Gui{}
+	v := g.newView("test", 0, 0, width+1, height+1, OutputNormal)
+	for _, l := range lines {
+		line := []cell{}
+		for _, r := range l {
+			line = append(line, cell{chr: r, fgColor: ColorDefault, bgColor: ColorDefault})
+		}
+		v.lines.append(line)
+	}
+	return v
+}
+
+// assertBuffer checks the buffer lines and the cursor position of a view.
+func assertBuffer(t *testing.T, v *View, cx, cy int, lines ...string) {
+	t.Helper()
+	if lines == nil {
+		lines = []string{}
+	}
+	if got := v.BufferLines(); !reflect.DeepEqual(got, lines) {
+		t.Errorf("Expected buffer lines to be: %q got: %q", lines, got)
+	}
+	if x, y := v.Cursor(); x != cx || y != cy {
+		t.Errorf("Expected cursor to be at (%d, %d) got: (%d, %d)", cx, cy, x, y)
+	}
+}
+
+func TestEditDeleteWord(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  []string
+		cx, cy int
+		wantX  int
+		wantY  int
+		want   []string
+	}{
+		{"mid word", []string{"hello world"}, 8, 0, 6, 0, []string{"hello rld"}},
+		{"end of word", []string{"hello world"}, 11, 0, 6, 0, []string{"hello "}},
+		{"trailing whitespace", []string{"foo bar  "}, 9, 0, 4, 0, []string{"foo "}},
+		{"punctuation", []string{"foo.bar"}, 7, 0, 4, 0, []string{"foo."}},
+		{"after punctuation", []string{"foo."}, 4, 0, 0, 0, []string{""}},
+		{"start of line", []string{"foo", "bar"}, 0, 1, 3, 0, []string{"foobar"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			v.EditDeleteWord()
+			assertBuffer(t, v, tt.wantX, tt.wantY, tt.want...)
+		})
+	}
+}
+
+func TestEditDeleteToEndOfLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  []string
+		cx, cy int
+		want   []string
+	}{
+		{"middle of line", []string{"hello world", "next"}, 5, 0, []string{"hello", "next"}},
+		{"start of line", []string{"hello world", "next"}, 0, 0, []string{"", "next"}},
+		{"last character", []string{"hello world", "next"}, 10, 0, []string{"hello worl", "next"}},
+		{"end of line", []string{"hello", "next"}, 5, 0, []string{"hellonext"}},
+		{"end of last line", []string{"hello"}, 5, 0, []string{"hello"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			v.EditDeleteToEndOfLine()
+			assertBuffer(t, v, tt.cx, tt.cy, tt.want...)
+		})
+	}
+}
+
+func TestMoveCursorWord(t *testing.T) {
+	lines := []string{"  foo bar  ", "foo.bar", ""}
+	tests := []struct {
+		name         string
+		cx, cy       int
+		right        bool
+		wantX, wantY int
+	}{
+		{"right over leading whitespace", 0, 0, true, 2, 0},
+		{"right to next word", 2, 0, true, 6, 0},
+		{"right over trailing whitespace", 6, 0, true, 11, 0},
+		{"right across line break", 11, 0, true, 0, 1},
+		{"right over punctuation", 0, 1, true, 4, 1},
+		{"right on last line", 0, 2, true, 0, 2},
+		{"left to start of word", 8, 0, false, 6, 0},
+		{"left over trailing whitespace", 11, 0, false, 6, 0},
+		{"left over leading whitespace", 2, 0, false, 0, 0},
+		{"left across line break", 0, 1, false, 11, 0},
+		{"left over punctuation", 4, 1, false, 0, 1},
+		{"left on first line", 0, 0, false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			if tt.right {
+				v.MoveCursorWordRight()
+			} else {
+				v.MoveCursorWordLeft()
+			}
+			assertBuffer(t, v, tt.wantX, tt.wantY, lines...)
+		})
+	}
+}
+
+func TestMoveCursorInLine(t *testing.T) {
+	lines := []string{"foo bar", "  \tindented ", "", "   "}
+	tests := []struct {
+		name         string
+		move         func(v *View)
+		cx, cy       int
+		wantX, wantY int
+	}{
+		{"start", (*View).MoveCursorToLineStart, 5, 0, 0, 0},
+		{"start of indented line", (*View).MoveCursorToLineStart, 6, 1, 0, 1},
+		{"start of empty line", (*View).MoveCursorToLineStart, 0, 2, 0, 2},
+		{"end", (*View).MoveCursorToLineEnd, 2, 0, 7, 0},
+		{"end of indented line", (*View).MoveCursorToLineEnd, 0, 1, 12, 1},
+		{"end of empty line", (*View).MoveCursorToLineEnd, 0, 2, 0, 2},
+		{"first non-blank", (*View).MoveCursorToFirstNonBlank, 5, 0, 0, 0},
+		{"first non-blank of indented line", (*View).MoveCursorToFirstNonBlank, 10, 1, 3, 1},
+		{"first non-blank from the indentation", (*View).MoveCursorToFirstNonBlank, 0, 1, 3, 1},
+		{"first non-blank of empty line", (*View).MoveCursorToFirstNonBlank, 0, 2, 0, 2},
+		{"first non-blank of blank line", (*View).MoveCursorToFirstNonBlank, 0, 3, 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			tt.move(v)
+			assertBuffer(t, v, tt.wantX, tt.wantY, lines...)
+		})
+	}
+
+	// The view isn't scrolled back if the start of the line is visible
+	v := newTestView(5, 2, "a", "b", "c", "d")
+	v.SetOrigin(0, 1)
+	v.SetCursor(1, 2)
+	v.MoveCursorToLineStart()
+	v.MoveCursorToLineEnd()
+	v.MoveCursorToFirstNonBlank()
+	if _, oy := v.Origin(); oy != 1 {
+		t.Errorf("Expected the origin to stay at row 1 got: %d", oy)
+	}
+
+	// The empty buffer
+	v = newTestView(5, 2)
+	v.MoveCursorToLineEnd()
+	v.MoveCursorToFirstNonBlank()
+	v.MoveCursorToLineStart()
+	assertBuffer(t, v, 0, 0)
+}
+
+func TestMoveCursorGoalColumn(t *testing.T) {
+	v := newTestView(20, 5, "a long line", "ab", "", "\tanother line")
+	v.TabWidth = 4
+	v.SetCursor(9, 0)
+
+	for _, want := range []struct{ x, y int }{{2, 1}, {0, 2}, {6, 3}, {0, 2}, {2, 1}, {9, 0}} {
+		if want.y > v.cy {
+			v.MoveCursor(0, 1)
+		} else {
+			v.MoveCursor(0, -1)
+		}
+		if x, y := v.Cursor(); x != want.x || y != want.y {
+			t.Fatalf("Expected cursor to be at (%d, %d) got: (%d, %d)", want.x, want.y, x, y)
+		}
+	}
+
+	// Horizontal moves reset the goal column
+	v.MoveCursor(0, 1)
+	v.MoveCursor(-1, 0)
+	v.MoveCursor(0, -1)
+	if x, y := v.Cursor(); x != 1 || y != 0 {
+		t.Errorf("Expected cursor to be at (1, 0) got: (%d, %d)", x, y)
+	}
+
+	// So do edits
+	v.SetCursor(9, 0)
+	v.MoveCursor(0, 1)
+	v.EditWrite('c')
+	v.MoveCursor(0, -1)
+	if x, y := v.Cursor(); x != 3 || y != 0 {
+		t.Errorf("Expected cursor to be at (3, 0) got: (%d, %d)", x, y)
+	}
+}
+
+func TestScrollMargin(t *testing.T) {
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	v := newTestView(10, 6, lines...)
+	v.ScrollMargin = 2
+	_, maxY := v.Size()
+
+	check := func() {
+		t.Helper()
+		_, oy := v.Origin()
+		above, below := v.cy-oy, oy+maxY-1-v.cy
+		if above < 0 || below < 0 {
+			t.Fatalf("Cursor on line %d isn't visible with y-origin %d", v.cy, oy)
+		}
+		if above < 2 && oy != 0 {
+			t.Fatalf("Cursor on line %d has %d rows above it with y-origin %d", v.cy, above, oy)
+		}
+		if below < 2 && oy+maxY != len(lines) {
+			t.Fatalf("Cursor on line %d has %d rows below it with y-origin %d", v.cy, below, oy)
+		}
+	}
+
+	for i := 0; i < len(lines)-1; i++ {
+		v.MoveCursor(0, 1)
+		check()
+	}
+	if _, oy := v.Origin(); oy != 14 {
+		t.Errorf("Expected y-origin 14 at the end of the buffer got: %d", oy)
+	}
+	for i := 0; i < len(lines)-1; i++ {
+		v.MoveCursor(0, -1)
+		check()
+	}
+
+	// Jumps keep the margin too
+	v.MoveCursor(0, 10)
+	check()
+	v.MoveCursor(0, -7)
+	check()
+
+	// The margin is reduced to fit in the view
+	v.MoveCursorToBufferStart()
+	v.ScrollMargin = 10
+	for i := 0; i < 5; i++ {
+		v.MoveCursor(0, 1)
+	}
+	if _, oy := v.Origin(); v.cy-oy != 3 {
+		t.Errorf("Expected the cursor on row 3 of the view got: %d", v.cy-oy)
+	}
+}
+
+func TestSideScrollMargin(t *testing.T) {
+	line := "0123456789abcdefghij"
+	v := newTestView(8, 3, line)
+	v.SideScrollMargin = 3
+	maxX, _ := v.Size()
+
+	check := func() {
+		t.Helper()
+		ox, _ := v.Origin()
+		left, right := v.cx-ox, ox+maxX-1-v.cx
+		if left < 0 || right < 0 {
+			t.Fatalf("Cursor at %d isn't visible with x-origin %d", v.cx, ox)
+		}
+		if left < 3 && ox != 0 {
+			t.Fatalf("Cursor at %d has %d columns on its left with x-origin %d", v.cx, left, ox)
+		}
+		if right < 3 && ox+maxX-1 != len(line) {
+			t.Fatalf("Cursor at %d has %d columns on its right with x-origin %d", v.cx, right, ox)
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		v.MoveCursor(1, 0)
+		check()
+	}
+	if ox, _ := v.Origin(); ox != 13 {
+		t.Errorf("Expected x-origin 13 at the end of the line got: %d", ox)
+	}
+	for i := 0; i < len(line); i++ {
+		v.MoveCursor(-1, 0)
+		check()
+	}
+
+	// Without a margin the cursor can reach the edges
+	v.SideScrollMargin = 0
+	for i := 0; i < 7; i++ {
+		v.MoveCursor(1, 0)
+	}
+	if ox, _ := v.Origin(); ox != 0 {
+		t.Errorf("Expected x-origin 0 got: %d", ox)
+	}
+	v.MoveCursor(1, 0)
+	if ox, _ := v.Origin(); ox != 1 {
+		t.Errorf("Expected x-origin 1 got: %d", ox)
+	}
+
+	// but 2 columns are kept visible on the left
+	v.SetOrigin(5, 0)
+	v.SetCursor(6, 0)
+	v.MoveCursor(-1, 0)
+	if ox, _ := v.Origin(); ox != 3 {
+		t.Errorf("Expected x-origin 3 got: %d", ox)
+	}
+}
+
+func TestMoveCursorBufferAndParagraph(t *testing.T) {
+	lines := []string{"first", "paragraph", "", "  ", "second", "", ""}
+	tests := []struct {
+		name         string
+		cx, cy       int
+		move         func(v *View)
+		wantX, wantY int
+	}{
+		{"buffer start", 3, 4, (*View).MoveCursorToBufferStart, 0, 0},
+		{"buffer end", 3, 1, (*View).MoveCursorToBufferEnd, 0, 6},
+		{"paragraph down", 2, 0, (*View).MoveCursorParagraphDown, 0, 2},
+		{"paragraph down over blank lines", 0, 2, (*View).MoveCursorParagraphDown, 0, 5},
+		{"paragraph down over trailing blank lines", 0, 5, (*View).MoveCursorParagraphDown, 0, 6},
+		{"paragraph up", 2, 4, (*View).MoveCursorParagraphUp, 0, 3},
+		{"paragraph up over blank lines", 0, 3, (*View).MoveCursorParagraphUp, 0, 0},
+		{"paragraph up from trailing blank lines", 0, 6, (*View).MoveCursorParagraphUp, 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 3, lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			tt.move(v)
+			assertBuffer(t, v, tt.wantX, tt.wantY, lines...)
+		})
+	}
+
+	t.Run("origin follows the cursor", func(t *testing.T) {
+		v := newTestView(20, 3, lines...)
+		v.MoveCursorToBufferEnd()
+		if _, oy := v.Origin(); oy != 4 {
+			t.Errorf("Expected origin y to be 4 got: %d", oy)
+		}
+		v.MoveCursorToBufferStart()
+		if _, oy := v.Origin(); oy != 0 {
+			t.Errorf("Expected origin y to be 0 got: %d", oy)
+		}
+	})
+
+	t.Run("empty buffer", func(t *testing.T) {
+		v := newTestView(20, 3)
+		for _, move := range []func(v *View){
+			(*View).MoveCursorToBufferStart, (*View).MoveCursorToBufferEnd,
+			(*View).MoveCursorParagraphDown, (*View).MoveCursorParagraphUp,
+		} {
+			move(v)
+			assertBuffer(t, v, 0, 0)
+		}
+	})
+}
+
+func TestEditInsertTab(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		cx         int
+		expandTabs bool
+		want       string
+		wantX      int
+	}{
+		{"column 0", "abc", 0, true, "        abc", 8},
+		{"column 3", "abc", 3, true, "abc     ", 8},
+		{"column 8", "abcdefgh", 8, true, "abcdefgh        ", 16},
+		{"after tab", "\tab", 3, true, "\tab      ", 9},
+		{"no expansion", "abc", 3, false, "abc\t", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(40, 5, tt.line)
+			v.ExpandTabs = tt.expandTabs
+			v.SetCursor(tt.cx, 0)
+			simpleEditor(v, KeyTab, 0, 0)
+			assertBuffer(t, v, tt.wantX, 0, tt.want)
+
+			v.Undo()
+			assertBuffer(t, v, tt.cx, 0, tt.line)
+		})
+	}
+}
+
+func TestEditWriteString(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []string
+		cx, cy       int
+		overwrite    bool
+		s            string
+		wantX, wantY int
+		want         []string
+	}{
+		{"single line", []string{"ab"}, 1, 0, false, "xyz", 4, 0, []string{"axyzb"}},
+		{"multi line", []string{"ab"}, 1, 0, false, "x\ny\nz", 1, 2, []string{"ax", "y", "zb"}},
+		{"trailing newline", []string{"ab"}, 2, 0, false, "cd\n", 0, 1, []string{"abcd", ""}},
+		{"tabs", []string{"ab"}, 0, 0, false, "\tx\t", 3, 0, []string{"\tx\tab"}},
+		{"wide runes", []string{"ab"}, 1, 0, false, "世界", 3, 0, []string{"a世界b"}},
+		{"overwrite", []string{"abcd"}, 1, 0, true, "xy", 3, 0, []string{"axyd"}},
+		{"overwrite past end", []string{"ab"}, 1, 0, true, "xyz", 4, 0, []string{"axyz"}},
+		{"empty buffer", nil, 0, 0, false, "a\nb", 1, 1, []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.lines...)
+			v.Overwrite = tt.overwrite
+			v.SetCursor(tt.cx, tt.cy)
+			v.EditWriteString(tt.s)
+			assertBuffer(t, v, tt.wantX, tt.wantY, tt.want...)
+
+			v.Undo()
+			assertBuffer(t, v, tt.cx, tt.cy, tt.lines...)
+		})
+	}
+}
+
+func TestCombiningMarks(t *testing.T) {
+	const acute = '\u0301'
+
+	t.Run("mid line", func(t *testing.T) {
+		v := newTestView(20, 5, "cafe!")
+		v.SetCursor(4, 0)
+		v.EditWrite(acute)
+		assertBuffer(t, v, 4, 0, "cafe\u0301!")
+		if x, _, _ := v.linesPosOnScreen(5, 0); x != 5 {
+			t.Errorf("Expected end of line on screen at column 5 got: %d", x)
+		}
+
+		v.EditDelete(true)
+		assertBuffer(t, v, 3, 0, "caf!")
+		v.Undo()
+		assertBuffer(t, v, 4, 0, "cafe\u0301!")
+		v.Undo()
+		assertBuffer(t, v, 4, 0, "cafe!")
+	})
+
+	t.Run("start of line", func(t *testing.T) {
+		v := newTestView(20, 5, "abc")
+		v.EditWrite(acute)
+		assertBuffer(t, v, 1, 0, "\u0301abc")
+		if x, _, _ := v.linesPosOnScreen(1, 0); x != 1 {
+			t.Errorf("Expected cursor on screen at column 1 got: %d", x)
+		}
+
+		drawTestView(t, v)
+		ch, comb, _, _ := screen.GetContent(v.x0+1, v.y0+1)
+		if ch != ' ' || !reflect.DeepEqual(comb, []rune{acute}) {
+			t.Errorf("Expected mark drawn on a space got: %q %q", ch, comb)
+		}
+
+		v.EditDelete(true)
+		assertBuffer(t, v, 0, 0, "abc")
+	})
+
+	t.Run("draw", func(t *testing.T) {
+		v := newTestView(20, 5)
+		v.SetContent([]string{"e\u0301\u0302x"})
+		assertBuffer(t, v, 0, 0, "e\u0301\u0302x")
+
+		drawTestView(t, v)
+		ch, comb, _, _ := screen.GetContent(v.x0+1, v.y0+1)
+		if ch != 'e' || !reflect.DeepEqual(comb, []rune{acute, '\u0302'}) {
+			t.Errorf("Expected marks drawn on 'e' got: %q %q", ch, comb)
+		}
+		if ch, _ := viewCell(v, 1, 0); ch != 'x' {
+			t.Errorf("Expected 'x' at column 1 got: %q", ch)
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		v := newTestView(20, 5)
+		v.Write([]byte("e\u0301x"))
+		if got := v.Buffer(); got != "e\u0301x" {
+			t.Errorf("Expected buffer to be: %q got: %q", "e\u0301x", got)
+		}
+		if got := len(v.lines.line(0)); got != 2 {
+			t.Errorf("Expected 2 cells got: %d", got)
+		}
+	})
+}
+
+func TestEditTransposeChars(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  []string
+		cx, cy int
+		wantX  int
+		want   []string
+	}{
+		{"mid line", []string{"abcd"}, 2, 0, 3, []string{"acbd"}},
+		{"end of line", []string{"abcd"}, 4, 0, 4, []string{"abdc"}},
+		{"before last character", []string{"abcd"}, 3, 0, 4, []string{"abdc"}},
+		{"start of line", []string{"abcd", "ef"}, 0, 1, 0, []string{"abcd", "ef"}},
+		{"single character line", []string{"a"}, 1, 0, 1, []string{"a"}},
+		{"empty line", []string{""}, 0, 0, 0, []string{""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			v.EditTransposeChars()
+			assertBuffer(t, v, tt.wantX, tt.cy, tt.want...)
+
+			v.Undo()
+			assertBuffer(t, v, tt.cx, tt.cy, tt.lines...)
+		})
+	}
+}
+
+func TestEditCaseWord(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		cx    int
+		edit  func(v *View)
+		wantX int
+		want  string
+	}{
+		{"upcase", "hello World", 0, (*View).EditUpcaseWord, 5, "HELLO World"},
+		{"upcase mixed case", "hElLo world", 0, (*View).EditUpcaseWord, 5, "HELLO world"},
+		{"upcase from whitespace", "hello world", 5, (*View).EditUpcaseWord, 11, "hello WORLD"},
+		{"upcase mid word", "hello world", 2, (*View).EditUpcaseWord, 5, "heLLO world"},
+		{"upcase with punctuation", "foo, bar", 0, (*View).EditUpcaseWord, 3, "FOO, bar"},
+		{"downcase", "HeLLO WORLD", 0, (*View).EditDowncaseWord, 5, "hello WORLD"},
+		{"downcase with punctuation", "  FOO.BAR", 0, (*View).EditDowncaseWord, 5, "  foo.BAR"},
+		{"capitalize", "hELLO world", 0, (*View).EditCapitalizeWord, 5, "Hello world"},
+		{"capitalize from punctuation", "foo... bAR!", 3, (*View).EditCapitalizeWord, 10, "foo... Bar!"},
+		{"no word", "foo  ", 3, (*View).EditUpcaseWord, 5, "foo  "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.line)
+			v.SetCursor(tt.cx, 0)
+			v.tainted = false
+			tt.edit(v)
+			assertBuffer(t, v, tt.wantX, 0, tt.want)
+			if v.IsTainted() != (tt.want != tt.line) {
+				t.Errorf("Expected view tainted to be %v", tt.want != tt.line)
+			}
+		})
+	}
+}
+
+func TestEditDuplicateLine(t *testing.T) {
+	lines := []string{"first", "middle", "last"}
+	tests := []struct {
+		name   string
+		cx, cy int
+		want   []string
+	}{
+		{"first line", 2, 0, []string{"first", "first", "middle", "last"}},
+		{"middle line", 6, 1, []string{"first", "middle", "middle", "last"}},
+		{"last line", 0, 2, []string{"first", "middle", "last", "last"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.lines.line(tt.cy)[0].fgColor = ColorRed | AttrBold
+			v.lines.line(tt.cy)[1].bgColor = ColorBlue
+			v.SetCursor(tt.cx, tt.cy)
+			v.EditDuplicateLine()
+			assertBuffer(t, v, tt.cx, tt.cy, tt.want...)
+
+			if !reflect.DeepEqual(v.lines.line(tt.cy), v.lines.line(tt.cy+1)) {
+				t.Errorf("Expected cells to be copied, got: %v and %v", v.lines.line(tt.cy), v.lines.line(tt.cy+1))
+			}
+			v.lines.line(tt.cy + 1)[0].chr = 'x'
+			if v.lines.line(tt.cy)[0].chr == 'x' {
+				t.Error("Expected duplicated line to not share cells with the original")
+			}
+
+			v.Undo()
+			assertBuffer(t, v, tt.cx, tt.cy, lines...)
+		})
+	}
+
+	t.Run("empty buffer", func(t *testing.T) {
+		v := newTestView(20, 5)
+		v.EditDuplicateLine()
+		assertBuffer(t, v, 0, 0)
+	})
+}
+
+func TestEditDeleteLine(t *testing.T) {
+	lines := []string{"first", "middle", "last"}
+	tests := []struct {
+		name   string
+		cx, cy int
+		wantY  int
+		want   []string
+	}{
+		{"first line", 2, 0, 0, []string{"middle", "last"}},
+		{"middle line", 6, 1, 1, []string{"first", "last"}},
+		{"last line", 1, 2, 1, []string{"first", "middle"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			v.EditDeleteLine()
+			assertBuffer(t, v, 0, tt.wantY, tt.want...)
+			if got, want := v.LastKill(), lines[tt.cy]+"\n"; got != want {
+				t.Errorf("Expected kill %q got: %q", want, got)
+			}
+
+			v.Undo()
+			assertBuffer(t, v, tt.cx, tt.cy, lines...)
+		})
+	}
+
+	t.Run("only line", func(t *testing.T) {
+		v := newTestView(20, 5, "only")
+		v.SetCursor(2, 0)
+		v.EditDeleteLine()
+		assertBuffer(t, v, 0, 0)
+	})
+
+	t.Run("yank", func(t *testing.T) {
+		v := newTestView(20, 5, lines...)
+		v.SetCursor(0, 1)
+		v.EditDeleteLine()
+		v.EditYank()
+		assertBuffer(t, v, 0, 2, lines...)
+	})
+}
+
+func TestEditMoveLine(t *testing.T) {
+	lines := []string{"first", "second", "third"}
+	tests := []struct {
+		name  string
+		cy    int
+		up    bool
+		wantY int
+		want  []string
+	}{
+		{"up", 1, true, 0, []string{"second", "first", "third"}},
+		{"up from last line", 2, true, 1, []string{"first", "third", "second"}},
+		{"up past first line", 0, true, 0, lines},
+		{"down", 1, false, 2, []string{"first", "third", "second"}},
+		{"down from first line", 0, false, 1, []string{"second", "first", "third"}},
+		{"down past last line", 2, false, 2, lines},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.lines.line(tt.cy)[0].fgColor = ColorGreen
+			v.SetCursor(3, tt.cy)
+			if tt.up {
+				v.EditMoveLineUp()
+			} else {
+				v.EditMoveLineDown()
+			}
+			assertBuffer(t, v, 3, tt.wantY, tt.want...)
+			if fg := v.lines.line(tt.wantY)[0].fgColor; fg != ColorGreen {
+				t.Errorf("Expected moved line to keep its colors, got: %v", fg)
+			}
+
+			v.Undo()
+			assertBuffer(t, v, 3, tt.cy, lines...)
+		})
+	}
+}
+
+func TestEditJoinLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  []string
+		cx, cy int
+		wantX  int
+		want   []string
+	}{
+		{"no leading whitespace", []string{"foo", "bar"}, 1, 0, 3, []string{"foo bar"}},
+		{"leading whitespace", []string{"foo", " \t  bar", "baz"}, 0, 0, 3, []string{"foo bar", "baz"}},
+		{"trailing whitespace", []string{"foo ", "  bar"}, 0, 0, 4, []string{"foo bar"}},
+		{"empty next line", []string{"foo", "   ", "bar"}, 0, 0, 3, []string{"foo", "bar"}},
+		{"empty current line", []string{"", "  bar"}, 0, 0, 0, []string{"bar"}},
+		{"middle line", []string{"a", "b", "c"}, 0, 1, 1, []string{"a", "b c"}},
+		{"last line", []string{"foo", "bar"}, 1, 1, 1, []string{"foo", "bar"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			v.EditJoinLines()
+			assertBuffer(t, v, tt.wantX, tt.cy, tt.want...)
+
+			v.Undo()
+			assertBuffer(t, v, tt.cx, tt.cy, tt.lines...)
+		})
+	}
+}
+
+func TestWordDelimiter(t *testing.T) {
+	snakeCase := func(r rune) bool {
+		return r != '_' && DefaultWordDelimiter(r)
+	}
+	spaces := func(r rune) bool {
+		return r == ' '
+	}
+
+	tests := []struct {
+		name      string
+		delimiter func(r rune) bool
+		line      string
+		cx        int
+		edit      func(v *View)
+		wantX     int
+		want      string
+	}{
+		{"default word right", nil, "snake_case word", 0, (*View).MoveCursorWordRight, 6, "snake_case word"},
+		{"snake case word right", snakeCase, "snake_case word", 0, (*View).MoveCursorWordRight, 11, "snake_case word"},
+		{"snake case word left", snakeCase, "foo snake_case", 14, (*View).MoveCursorWordLeft, 4, "foo snake_case"},
+		{"snake case delete word", snakeCase, "foo snake_case", 14, (*View).EditDeleteWord, 4, "foo "},
+		{"snake case upcase", snakeCase, "snake_case-word", 0, (*View).EditUpcaseWord, 10, "SNAKE_CASE-word"},
+		{"spaces delete word", spaces, "a foo.bar-baz", 13, (*View).EditDeleteWord, 2, "a "},
+		{"spaces downcase", spaces, "FOO.BAR BAZ", 0, (*View).EditDowncaseWord, 7, "foo.bar BAZ"},
+		{"whitespace word right", unicode.IsSpace, "foo.bar\tbaz", 0, (*View).MoveCursorWordRight, 8, "foo.bar\tbaz"},
+		{"whitespace word left", unicode.IsSpace, "a foo.bar", 9, (*View).MoveCursorWordLeft, 2, "a foo.bar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.line)
+			v.WordDelimiter = tt.delimiter
+			v.SetCursor(tt.cx, 0)
+			tt.edit(v)
+			assertBuffer(t, v, tt.wantX, 0, tt.want)
+		})
+	}
+}
+
+func TestEditClearToBufferEnd(t *testing.T) {
+	lines := []string{"first", "second", "third"}
+	tests := []struct {
+		name   string
+		cx, cy int
+		want   []string
+	}{
+		{"mid buffer", 3, 1, []string{"first", "sec"}},
+		{"start of line", 0, 1, []string{"first", ""}},
+		{"last line", 2, 2, []string{"first", "second", "th"}},
+		{"end of buffer", 5, 2, lines},
+		{"start of buffer", 0, 0, nil},
+		{"past end of line", 9, 0, []string{"first"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.SetCursorUnrestricted(tt.cx, tt.cy)
+			v.tainted = false
+			v.EditClearToBufferEnd()
+			assertBuffer(t, v, tt.cx, tt.cy, tt.want...)
+			if len(tt.want) < len(lines) && !v.IsTainted() {
+				t.Error("Expected view to be tainted")
+			}
+
+			v.Undo()
+			assertBuffer(t, v, tt.cx, tt.cy, lines...)
+		})
+	}
+}
+
+func TestAutoPair(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		cx        int
+		typed     string
+		backspace int
+		wantX     int
+		want      string
+	}{
+		{"parenthesis", "", 0, "(", 0, 1, "()"},
+		{"brackets and braces", "", 0, "[{", 0, 2, "[{}]"},
+		{"quotes", "", 0, "\"`", 0, 2, "\"``\""},
+		{"type inside", "", 0, "(ab", 0, 3, "(ab)"},
+		{"type over", "", 0, "(a)", 0, 3, "(a)"},
+		{"type over quote", "", 0, "\"a\"", 0, 3, "\"a\""},
+		{"type over existing", "f(x)", 3, ")", 0, 4, "f(x)"},
+		{"closing not at cursor", "ab", 2, ")", 0, 3, "ab)"},
+		{"other characters", "", 0, "a<", 0, 2, "a<"},
+		{"delete empty pair", "", 0, "(", 1, 0, ""},
+		{"delete nested pairs", "", 0, "([", 2, 0, ""},
+		{"delete in non empty pair", "", 0, "(a", 1, 1, "()"},
+		{"delete after pair", "", 0, "()", 1, 1, "("},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.line)
+			v.AutoPair = true
+			v.SetCursor(tt.cx, 0)
+			typeString(v, tt.typed)
+			for i := 0; i < tt.backspace; i++ {
+				v.Editor.Edit(v, KeyBackspace2, 0, ModNone)
+			}
+			assertBuffer(t, v, tt.wantX, 0, tt.want)
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		v := newTestView(20, 5, "")
+		typeString(v, "(\"")
+		v.Editor.Edit(v, KeyArrowLeft, 0, ModNone)
+		v.Editor.Edit(v, KeyBackspace2, 0, ModNone)
+		assertBuffer(t, v, 0, 0, "\"")
+	})
+}
+
+func TestAutoIndent(t *testing.T) {
+	tests := []struct {
+		name       string
+		line       string
+		cx         int
+		autoIndent bool
+		wantX      int
+		want       []string
+	}{
+		{"spaces", "    foo bar", 8, true, 4, []string{"    foo ", "    bar"}},
+		{"tabs", "\t\tfoo", 5, true, 2, []string{"\t\tfoo", "\t\t"}},
+		{"mixed", " \tfoo", 5, true, 2, []string{" \tfoo", " \t"}},
+		{"no indentation", "foo bar", 4, true, 0, []string{"foo ", "bar"}},
+		{"cursor in indentation", "    foo", 2, true, 2, []string{"  ", "    foo"}},
+		{"cursor at start", "    foo", 0, true, 0, []string{"", "    foo"}},
+		{"disabled", "    foo", 7, false, 0, []string{"    foo", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.line)
+			v.AutoIndent = tt.autoIndent
+			v.SetCursor(tt.cx, 0)
+			v.EditNewLine()
+			assertBuffer(t, v, tt.wantX, 1, tt.want...)
+
+			v.Undo()
+			assertBuffer(t, v, tt.cx, 0, tt.line)
+		})
+	}
+}
+
+func TestEditOpenLine(t *testing.T) {
+	lines := []string{"first", "  \tsecond", "third"}
+	tests := []struct {
+		name       string
+		above      bool
+		autoIndent bool
+		wantX      int
+		wantY      int
+		want       []string
+	}{
+		{"below", false, false, 0, 2, []string{"first", "  \tsecond", "", "third"}},
+		{"above", true, false, 0, 1, []string{"first", "", "  \tsecond", "third"}},
+		{"below with indent", false, true, 3, 2, []string{"first", "  \tsecond", "  \t", "third"}},
+		{"above with indent", true, true, 3, 1, []string{"first", "  \t", "  \tsecond", "third"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.AutoIndent = tt.autoIndent
+			v.SetCursor(5, 1)
+			if tt.above {
+				v.EditOpenLineAbove()
+			} else {
+				v.EditOpenLineBelow()
+			}
+			assertBuffer(t, v, tt.wantX, tt.wantY, tt.want...)
+
+			v.Undo()
+			assertBuffer(t, v, 5, 1, lines...)
+		})
+	}
+
+	t.Run("empty buffer", func(t *testing.T) {
+		v := newTestView(20, 5)
+		v.EditOpenLineBelow()
+		assertBuffer(t, v, 0, 1, "", "")
+	})
+
+	t.Run("single line", func(t *testing.T) {
+		v := newTestView(20, 5, "foo")
+		v.SingleLine = true
+		v.EditOpenLineAbove()
+		assertBuffer(t, v, 0, 0, "foo")
+	})
+}
+
+func TestEditIndentLines(t *testing.T) {
+	lines := []string{"func f() {", "x := 1", "", "return x", "}"}
+	v := newTestView(20, 5, lines...)
+	v.SetCursor(2, 1)
+	v.SetSelection(0, 1, 0, 4)
+	v.EditIndentLines(4)
+	assertBuffer(t, v, 6, 1, "func f() {", "    x := 1", "", "    return x", "}")
+	if got, want := v.SelectedText(), "    x := 1\n\n    return x\n"; got != want {
+		t.Errorf("Expected selected text to be: %q got: %q", want, got)
+	}
+
+	v.EditIndentLines(0)
+	assertBuffer(t, v, 7, 1, "func f() {", "\t    x := 1", "", "\t    return x", "}")
+
+	v.ExpandTabs = true
+	v.TabWidth = 2
+	v.ClearSelection()
+	v.EditIndentLines(0)
+	assertBuffer(t, v, 9, 1, "func f() {", "  \t    x := 1", "", "\t    return x", "}")
+
+	v.Undo()
+	v.Undo()
+	v.Undo()
+	assertBuffer(t, v, 2, 1, lines...)
+}
+
+func TestEditDedentLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		n     int
+		cx    int
+		want  string
+		wantX int
+	}{
+		{"spaces", "      foo", 4, 7, "  foo", 3},
+		{"less than n", "  foo", 4, 3, "foo", 1},
+		{"no whitespace", "foo", 4, 1, "foo", 1},
+		{"tab", "\t  foo", 4, 0, "  foo", 0},
+		{"space and tab", "  \tfoo", 4, 4, "foo", 1},
+		{"tab after n", "    \tfoo", 4, 5, "\tfoo", 1},
+		{"cursor in removed whitespace", "      foo", 4, 2, "  foo", 0},
+		{"tab width", "\t\tfoo", 0, 3, "\tfoo", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.line)
+			v.TabWidth = 4
+			v.SetCursor(tt.cx, 0)
+			v.EditDedentLines(tt.n)
+			assertBuffer(t, v, tt.wantX, 0, tt.want)
+		})
+	}
+
+	v := newTestView(20, 5, "\tfoo", "  bar", "baz", "    qux")
+	v.TabWidth = 4
+	v.SetSelection(2, 0, 3, 3)
+	v.EditDedentLines(2)
+	assertBuffer(t, v, 0, 0, "foo", "bar", "baz", "  qux")
+}
+
+func TestEditToggleComment(t *testing.T) {
+	lines := []string{"if x {", "\tfoo()", "", "  bar()", "}"}
+	v := newTestView(20, 5, lines...)
+	v.SetCursor(2, 1)
+	v.SetSelection(0, 1, 7, 3)
+	v.EditToggleComment("//")
+	assertBuffer(t, v, 5, 1, "if x {", "\t// foo()", "", "  // bar()", "}")
+
+	v.EditToggleComment("//")
+	assertBuffer(t, v, 2, 1, lines...)
+
+	t.Run("mixed", func(t *testing.T) {
+		v := newTestView(20, 5, "# foo", "bar", "  #baz")
+		v.SetSelection(0, 0, 6, 2)
+		v.EditToggleComment("#")
+		assertBuffer(t, v, 2, 0, "# # foo", "# bar", "  # #baz")
+
+		v.EditToggleComment("#")
+		assertBuffer(t, v, 0, 0, "# foo", "bar", "  #baz")
+	})
+
+	t.Run("uncomment without space", func(t *testing.T) {
+		v := newTestView(20, 5, "#foo", "  # bar")
+		v.SetSelection(0, 0, 3, 1)
+		v.EditToggleComment("#")
+		assertBuffer(t, v, 0, 0, "foo", "  bar")
+
+		v.Undo()
+		assertBuffer(t, v, 0, 0, "#foo", "  # bar")
+	})
+}
+
+func TestReflowParagraph(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		cy    int
+		width int
+		wantY int
+		want  []string
+	}{
+		{"long line", []string{"the quick brown fox jumps"}, 0, 10, 0, []string{"the quick", "brown fox", "jumps"}},
+		{"short lines", []string{"a b", "c", "d  e f"}, 1, 5, 0, []string{"a b c", "d e f"}},
+		{"exact width", []string{"aaaa bbbb"}, 0, 9, 0, []string{"aaaa bbbb"}},
+		{"long word", []string{"a verylongword b"}, 0, 5, 0, []string{"a", "verylongword", "b"}},
+		{"paragraph bounds", []string{"keep me", "", "x y", "z", "  ", "keep"}, 3, 10, 2, []string{"keep me", "", "x y z", "  ", "keep"}},
+		{"indentation", []string{"  foo bar baz"}, 0, 9, 0, []string{"  foo bar", "  baz"}},
+		{"tab indentation", []string{"\tfoo bar", "baz"}, 0, 12, 0, []string{"\tfoo bar", "\tbaz"}},
+		{"blank line", []string{"foo", "", "bar"}, 1, 2, 1, []string{"foo", "", "bar"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.lines...)
+			v.TabWidth = 4
+			v.SetCursor(1, tt.cy)
+			cx, _ := v.Cursor()
+			v.ReflowParagraph(tt.width)
+			assertBuffer(t, v, 0, tt.wantY, tt.want...)
+
+			v.Undo()
+			assertBuffer(t, v, cx, tt.cy, tt.lines...)
+		})
+	}
+}
+
+func TestMaxLength(t *testing.T) {
+	v := newTestView(20, 5, "ab")
+	v.MaxLength = 4
+	refused := 0
+	v.OnMaxLength = func(*View) { refused++ }
+	v.SetCursor(2, 0)
+
+	v.EditWrite('c')
+	v.EditWrite('中')
+	v.EditWrite('d')
+	assertBuffer(t, v, 4, 0, "abc中")
+	if refused != 1 {
+		t.Errorf("Expected 1 refused rune got: %d", refused)
+	}
+
+	// Overwriting and combining marks don't make the content longer
+	v.Overwrite = true
+	v.SetCursor(0, 0)
+	v.EditWrite('x')
+	v.EditWrite('\u0301')
+	v.Overwrite = false
+	assertBuffer(t, v, 1, 0, "x\u0301bc中")
+
+	v = newTestView(20, 5, "ab")
+	v.MaxLength = 6
+	v.OnMaxLength = func(*View) { refused++ }
+	refused = 0
+	v.SetCursor(1, 0)
+	v.EditWriteString("1\n234")
+	assertBuffer(t, v, 2, 1, "a1", "23b")
+	if refused != 1 {
+		t.Errorf("Expected the string to be refused once got: %d", refused)
+	}
+	v.EditNewLine()
+	assertBuffer(t, v, 2, 1, "a1", "23b")
+}
+
+func TestMaxColumn(t *testing.T) {
+	v := newTestView(20, 5, "ab", "abcd")
+	v.MaxColumn = 4
+	refused := 0
+	v.OnMaxLength = func(*View) { refused++ }
+	v.SetCursor(2, 0)
+
+	v.EditWriteString("cde")
+	assertBuffer(t, v, 4, 0, "abcd", "abcd")
+	if refused != 1 {
+		t.Errorf("Expected 1 refused rune got: %d", refused)
+	}
+
+	// Inserting in a full line is refused, overwriting isn't
+	v.SetCursor(1, 1)
+	v.EditWrite('x')
+	assertBuffer(t, v, 1, 1, "abcd", "abcd")
+	v.Overwrite = true
+	v.EditWrite('x')
+	assertBuffer(t, v, 2, 1, "abcd", "axcd")
+	if refused != 2 {
+		t.Errorf("Expected 2 refused runes got: %d", refused)
+	}
+
+	// Writing below the limit is accepted again
+	v.Overwrite = false
+	v.SetCursor(1, 1)
+	v.EditDelete(true)
+	v.EditWrite('y')
+	assertBuffer(t, v, 1, 1, "abcd", "yxcd")
+}
+
+func TestSingleLine(t *testing.T) {
+	v := newTestView(20, 5, "foo")
+	v.Editable = true
+	v.SingleLine = true
+	v.SetCursor(3, 0)
+
+	simpleEditor(v, KeyEnter, 0, ModNone)
+	simpleEditor(v, KeyArrowDown, 0, ModNone)
+	assertBuffer(t, v, 3, 0, "foo")
+
+	v.EditWriteString("\nbar\nbaz")
+	assertBuffer(t, v, 11, 0, "foo bar baz")
+
+	v.MoveCursor(-4, 2)
+	assertBuffer(t, v, 7, 0, "foo bar baz")
+}
+
+func TestOnChangeOnSubmit(t *testing.T) {
+	v := newTestView(20, 5)
+	v.Editable = true
+	v.UndoLimit = 10
+	changes, submits := 0, 0
+	var content string
+	v.OnChange = func(v *View) {
+		changes++
+		content = v.Buffer()
+	}
+	v.OnSubmit = func(v *View) {
+		submits++
+	}
+
+	for _, r := range "abc" {
+		simpleEditor(v, 0, r, ModNone)
+	}
+	if changes != 3 || content != "abc" {
+		t.Errorf("Expected 3 changes ending with %q got: %d %q", "abc", changes, content)
+	}
+	simpleEditor(v, KeyArrowLeft, 0, ModNone)
+	v.EditWriteString("xy")
+	if changes != 4 || content != "abxyc" {
+		t.Errorf("Expected 4 changes ending with %q got: %d %q", "abxyc", changes, content)
+	}
+	v.Undo()
+	if changes != 5 || content != "abc" {
+		t.Errorf("Expected 5 changes ending with %q got: %d %q", "abc", changes, content)
+	}
+
+	simpleEditor(v, KeyEnter, 0, ModNone)
+	if submits != 0 || changes != 6 {
+		t.Errorf("Expected a new line without submit got: %d submits %d changes", submits, changes)
+	}
+	v.SingleLine = true
+	simpleEditor(v, KeyEnter, 0, ModNone)
+	if submits != 1 || changes != 6 {
+		t.Errorf("Expected a submit without change got: %d submits %d changes", submits, changes)
+	}
+}
+
+func TestOnWrite(t *testing.T) {
+	v := newTestView(20, 5)
+	v.UndoLimit = 10
+	writes := 0
+	var content string
+	v.OnWrite = func(v *View) {
+		writes++
+		content = v.Buffer()
+	}
+
+	v.Write([]byte("ab"))
+	if writes != 1 || content != "ab" {
+		t.Errorf("Expected 1 write ending with %q got: %d %q", "ab", writes, content)
+	}
+	v.SetCursor(2, 0)
+	v.EditWrite('c')
+	v.EditDelete(true)
+	v.EditDelete(true)
+	if writes != 4 || content != "a" {
+		t.Errorf("Expected 4 writes ending with %q got: %d %q", "a", writes, content)
+	}
+
+	// Moving the cursor and edits that change nothing don't call it
+	v.MoveCursor(-1, 0)
+	v.EditDelete(true)
+	v.Undo()
+	if writes != 5 || content != "ab" {
+		t.Errorf("Expected 5 writes ending with %q got: %d %q", "ab", writes, content)
+	}
+
+	v.AppendLine("d")
+	v.SetContent([]string{"e"})
+	v.SetLine(0, "f")
+	if writes != 8 || content != "f" {
+		t.Errorf("Expected 8 writes ending with %q got: %d %q", "f", writes, content)
+	}
+	v.Clear()
+	if writes != 9 || content != "" {
+		t.Errorf("Expected 9 writes ending with %q got: %d %q", "", writes, content)
+	}
+}
+
+// newLargeTestView returns a view with n short lines and the cursor in the
+// middle of its buffer.
+func newLargeTestView(n int) *View {
+	v := newTestView(80, 25)
+	for i := 0; i < n; i++ {
+		v.lines.append([]cell{{chr: 'a'}, {chr: 'b'}})
+	}
+	v.UndoLimit = 0
+	v.cy = n / 2
+	return v
+}
+
+func BenchmarkEditNewLine(b *testing.B) {
+	v := newLargeTestView(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.cx, v.cy = 1, 50000
+		v.EditNewLine()
+	}
+}
+
+func BenchmarkEditDeleteLine(b *testing.B) {
+	v := newLargeTestView(100000 + b.N)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.cx, v.cy = 0, 50000
+		v.EditDeleteLine()
+	}
+}
+
+func BenchmarkEditDuplicateLine(b *testing.B) {
+	v := newLargeTestView(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		v.cy = 50000
+		v.EditDuplicateLine()
+		v.deleteLine(50000)
+	}
+}
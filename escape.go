@@ -27,6 +27,7 @@ const (
 	stateEscape
 	stateCSI
 	stateParams
+	stateIgnore
 
 	bold               fontEffect = 1
 	faint              fontEffect = 2
@@ -45,26 +46,6 @@ var (
 	errCSITooLong    = errors.New("CSI escape sequence is too long")
 )
 
-// runes in case of error will output the non-parsed runes as a string.
-func (ei *escapeInterpreter) runes() []rune {
-	switch ei.state {
-	case stateNone:
-		return []rune{0x1b}
-	case stateEscape:
-		return []rune{0x1b, ei.curch}
-	case stateCSI:
-		return []rune{0x1b, '[', ei.curch}
-	case stateParams:
-		ret := []rune{0x1b, '['}
-		for _, s := range ei.csiParam {
-			ret = append(ret, []rune(s)...)
-			ret = append(ret, ';')
-		}
-		return append(ret, ei.curch)
-	}
-	return nil
-}
-
 // newEscapeInterpreter returns an escapeInterpreter that will be able to parse
 // terminal escape sequences.
 func newEscapeInterpreter(mode OutputMode) *escapeInterpreter {
@@ -85,6 +66,12 @@ func (ei *escapeInterpreter) reset() {
 	ei.csiParam = nil
 }
 
+// abort drops the escape sequence being parsed, the current colors are kept.
+func (ei *escapeInterpreter) abort() {
+	ei.state = stateNone
+	ei.csiParam = nil
+}
+
 // parseOne parses a rune. If isEscape is true, it means that the rune is part
 // of an escape sequence, and as such should not be printed verbatim. Otherwise,
 // it's not an escape sequence.
@@ -119,7 +106,7 @@ func (ei *escapeInterpreter) parseOne(ch rune) (isEscape bool, err error) {
 		case ch == 'm':
 			ei.csiParam = append(ei.csiParam, "0")
 		default:
-			return false, errCSIParseError
+			return ei.ignore(ch)
 		}
 		ei.state = stateParams
 		fallthrough
@@ -149,13 +136,29 @@ func (ei *escapeInterpreter) parseOne(ch rune) (isEscape bool, err error) {
 			ei.csiParam = nil
 			return true, nil
 		default:
-			return false, errCSIParseError
+			return ei.ignore(ch)
 		}
+	case stateIgnore:
+		return ei.ignore(ch)
 	}
 	return false, nil
 }
 
-// outputNormal provides 8 different colors:
+// ignore skips the rest of a CSI escape sequence that is not supported, up
+// to its final byte.
+func (ei *escapeInterpreter) ignore(ch rune) (isEscape bool, err error) {
+	switch {
+	case ch >= 0x20 && ch <= 0x3f: // parameter and intermediate bytes
+		ei.state = stateIgnore
+		return true, nil
+	case ch >= 0x40 && ch <= 0x7e: // final byte
+		ei.abort()
+		return true, nil
+	}
+	return false, errCSIParseError
+}
+
+// outputNormal provides 8 different colors and their bright variants:
 //   black, red, green, yellow, blue, magenta, cyan, white
 func (ei *escapeInterpreter) outputNormal() error {
 	for _, param := range ei.csiParam {
@@ -164,15 +167,20 @@ func (ei *escapeInterpreter) outputNormal() error {
 			return errCSIParseError
 		}
 
+		// Setting a color keeps the font effects already set
 		switch {
 		case p >= 30 && p <= 37:
-			ei.curFgColor = Get256Color(int32(p) - 30)
+			ei.curFgColor = ei.curFgColor&AttrStyleBits | Get256Color(int32(p)-30)
 		case p == 39:
-			ei.curFgColor = ColorDefault
+			ei.curFgColor = ei.curFgColor & AttrStyleBits
 		case p >= 40 && p <= 47:
-			ei.curBgColor = Get256Color(int32(p) - 40)
+			ei.curBgColor = ei.curBgColor&AttrStyleBits | Get256Color(int32(p)-40)
 		case p == 49:
-			ei.curBgColor = ColorDefault
+			ei.curBgColor = ei.curBgColor & AttrStyleBits
+		case p >= 90 && p <= 97:
+			ei.curFgColor = ei.curFgColor&AttrStyleBits | Get256Color(int32(p)-90+8)
+		case p >= 100 && p <= 107:
+			ei.curBgColor = ei.curBgColor&AttrStyleBits | Get256Color(int32(p)-100+8)
 		case p == 0:
 			ei.curFgColor = ColorDefault
 			ei.curBgColor = ColorDefault
@@ -0,0 +1,104 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWriteEscapeSequences(t *testing.T) {
+	type cellColors struct {
+		chr    rune
+		fg, bg Attribute
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []cellColors
+	}{
+		{"plain", "ab", []cellColors{
+			{'a', ColorDefault, ColorDefault},
+			{'b', ColorDefault, ColorDefault},
+		}},
+		{"foreground and reset", "\x1b[31ma\x1b[0mb", []cellColors{
+			{'a', ColorRed, ColorDefault},
+			{'b', ColorDefault, ColorDefault},
+		}},
+		{"background", "\x1b[44ma\x1b[49mb", []cellColors{
+			{'a', ColorDefault, ColorBlue},
+			{'b', ColorDefault, ColorDefault},
+		}},
+		{"bright colors", "\x1b[91;102ma", []cellColors{
+			{'a', Get256Color(9), Get256Color(10)},
+		}},
+		{"font effect kept", "\x1b[1;32ma\x1b[39mb", []cellColors{
+			{'a', ColorGreen | AttrBold, ColorDefault},
+			{'b', ColorDefault | AttrBold, ColorDefault},
+		}},
+		{"short reset", "\x1b[33ma\x1b[mb", []cellColors{
+			{'a', ColorYellow, ColorDefault},
+			{'b', ColorDefault, ColorDefault},
+		}},
+		{"unsupported sequence", "\x1b[31ma\x1b[2Kb\x1b[?25lc", []cellColors{
+			{'a', ColorRed, ColorDefault},
+			{'b', ColorRed, ColorDefault},
+			{'c', ColorRed, ColorDefault},
+		}},
+		{"invalid sequence", "a\x1b[31;\x01b", []cellColors{
+			{'a', ColorDefault, ColorDefault},
+			{'b', ColorDefault, ColorDefault},
+		}},
+		{"not a CSI sequence", "a\x1b7b", []cellColors{
+			{'a', ColorDefault, ColorDefault},
+			{'b', ColorDefault, ColorDefault},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5)
+			v.Write([]byte(tt.input))
+			if v.lines.len() != 1 || len(v.lines.line(0)) != len(tt.want) {
+				t.Fatalf("Expected %d cells got: %q", len(tt.want), v.BufferLines())
+			}
+			for i, want := range tt.want {
+				c := v.lines.line(0)[i]
+				if got := (cellColors{c.chr, c.fgColor, c.bgColor}); got != want {
+					t.Errorf("Expected cell %d to be %+v got: %+v", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteSplit(t *testing.T) {
+	tests := []struct {
+		input, text string
+	}{
+		{"\x1b[1;31mred\x1b[0m plain", "red plain"},
+		{"h\u00e9llo \u4e16\u754c\U0001F600!", "h\u00e9llo \u4e16\u754c\U0001F600!"},
+		{"\x1b[44m\u4e16\x1b[49m\u00e9", "\u4e16\u00e9"},
+	}
+
+	for _, tt := range tests {
+		input := tt.input
+		want := newTestView(20, 5)
+		want.Write([]byte(input))
+		if got := want.Buffer(); got != tt.text {
+			t.Fatalf("got %q, want %q", got, tt.text)
+		}
+
+		for i := 1; i < len(input); i++ {
+			v := newTestView(20, 5)
+			v.Write([]byte(input[:i]))
+			v.Write([]byte(input[i:]))
+			if !reflect.DeepEqual(v.lines.slice(0, v.lines.len()), want.lines.slice(0, want.lines.len())) {
+				t.Errorf("%q split at %d: got %q, want %q", input, i, v.BufferLines(), want.BufferLines())
+			}
+		}
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // OutputMode represents an output mode, which determines how colors
@@ -60,6 +63,12 @@ const (
 	OutputSimulator
 )
 
+// Default values of the Gui settings.
+const (
+	defaultDoubleClickInterval = 500 * time.Millisecond
+	defaultKeySequenceTimeout  = time.Second
+)
+
 // Gui represents the whole User Interface, including the views, layouts
 // and keybindings.
 type Gui struct {
@@ -76,9 +85,59 @@ type Gui struct {
 	testCounter int // used for testing synchronization
 	testNotify  chan struct{}
 
+	// batchDepth is the number of running batches, the GUI isn't redrawn
+	// while it's not 0. It's accessed atomically.
+	batchDepth int32
+
+	// running is 1 while MainLoop runs, nothing handles the updates
+	// otherwise. It's accessed atomically.
+	running int32
+
+	// hasLoader is 1 if a view had a loader at the last flush, so that
+	// loaderTick redraws the animation. It's accessed atomically.
+	hasLoader int32
+
+	// closed is closed by Close, it stops MainLoop and the goroutines it
+	// started, which are tracked by loops
+	closed    chan struct{}
+	closeOnce sync.Once
+	loops     sync.WaitGroup
+
+	// tickStop stops the ticks started by SetTickInterval, it's protected
+	// by tickMutex
+	tickStop  chan struct{}
+	tickMutex sync.Mutex
+
 	// The position of the mouse
 	mouseX, mouseY int
 
+	// dragView is the view where the left mouse button was pressed, nil
+	// once it's released
+	dragView *View
+
+	// clickTime, clickX and clickY describe the last left click, clicks is
+	// the number of consecutive clicks at that position
+	clickTime      time.Time
+	clickX, clickY int
+	clicks         int
+
+	// pendingKeys are the keys typed in pendingView that may start a
+	// keybinding sequence, pendingTime is the time of the last one and
+	// pendingTimer the timer that will flush them, identified by pendingGen
+	pendingKeys  []keyPress
+	pendingView  *View
+	pendingTime  time.Time
+	pendingTimer *time.Timer
+	pendingGen   int
+
+	// pasting is true between the start and the end of a bracketed paste,
+	// pasted holds the runes pasted so far
+	pasting bool
+	pasted  []rune
+
+	// resized is true once OnResize was called with the current size
+	resized bool
+
 	// BgColor and FgColor allow to configure the background and foreground
 	// colors of the GUI.
 	BgColor, FgColor, FrameColor Attribute
@@ -97,6 +156,12 @@ type Gui struct {
 	// If Mouse is true then mouse events will be enabled.
 	Mouse bool
 
+	// If Paste is true then bracketed paste will be enabled: the text pasted
+	// in the terminal is given at once to the OnPaste callback of the current
+	// view, or written in it if it's editable, instead of being sent key by
+	// key. AutoIndent and AutoPair don't change the pasted text.
+	Paste bool
+
 	// If InputEsc is true, when ESC sequence is in the buffer and it doesn't
 	// match any known sequence, ESC means KeyEsc.
 	InputEsc bool
@@ -108,6 +173,33 @@ type Gui struct {
 	// SupportOverlaps is true when we allow for view edges to overlap with other
 	// view edges
 	SupportOverlaps bool
+
+	// DoubleClickInterval is the maximum delay between the clicks of a
+	// double or triple click. A double click selects the word under the
+	// mouse and a triple click selects the whole line.
+	DoubleClickInterval time.Duration
+
+	// KeySequenceTimeout is the maximum delay between the keys of a
+	// keybinding sequence, see SetKeybindingSequence.
+	KeySequenceTimeout time.Duration
+
+	// OnTick is called by the main loop at the interval set with
+	// SetTickInterval.
+	OnTick func(*Gui) error
+
+	// OnFocusChange is called by SetCurrentView when the focus moves from
+	// prev to next, once CurrentView returns next. prev is nil if no view
+	// owned the focus.
+	OnFocusChange func(g *Gui, prev, next *View)
+
+	// OnResize is called with the size of the terminal before the first
+	// layout and whenever it changes, before the managers are called, e.g.
+	// to compute the positions of the views.
+	OnResize func(g *Gui, width, height int)
+
+	// Clipboard is used by the EditCopy, EditCut and EditPaste helpers of
+	// the views. If it's nil, they use the kill ring of the view instead.
+	Clipboard Clipboard
 }
 
 // NewGui returns a new Gui object with a given output mode.
@@ -130,6 +222,7 @@ func NewGui(mode OutputMode, supportOverlaps bool) (*Gui, error) {
 	g.outputMode = mode
 
 	g.stop = make(chan struct{})
+	g.closed = make(chan struct{})
 
 	g.gEvents = make(chan gocuiEvent, 20)
 	g.userEvents = make(chan userEvent, 20)
@@ -147,6 +240,8 @@ func NewGui(mode OutputMode, supportOverlaps bool) (*Gui, error) {
 	g.mouseX, g.mouseY = -1, -1
 	g.BgColor, g.FgColor, g.FrameColor = ColorDefault, ColorDefault, ColorDefault
 	g.SelBgColor, g.SelFgColor, g.SelFrameColor = ColorDefault, ColorDefault, ColorDefault
+	g.DoubleClickInterval = defaultDoubleClickInterval
+	g.KeySequenceTimeout = defaultKeySequenceTimeout
 
 	// SupportOverlaps is true when we allow for view edges to overlap with other
 	// view edges
@@ -158,10 +253,14 @@ func NewGui(mode OutputMode, supportOverlaps bool) (*Gui, error) {
 // Close finalizes the library. It should be called after a successful
 // initialization and when gocui is not needed anymore.
 func (g *Gui) Close() {
-	go func() {
-		g.stop <- struct{}{}
-	}()
+	g.SetTickInterval(0)
+	g.stopPendingTimer()
+	g.closeOnce.Do(func() {
+		close(g.closed)
+	})
 	screen.Fini()
+	// The screen can be replaced once nothing polls it anymore
+	g.loops.Wait()
 }
 
 // Size returns the terminal's size.
@@ -196,6 +295,21 @@ func (g *Gui) Rune(x, y int) (rune, error) {
 	return c, nil
 }
 
+// ScreenBuffer returns the runes of the screen's buffer by row, i.e. what
+// has been drawn so far, including the cells that aren't shown on the
+// terminal yet. It's meant to write tests that check the layout of the GUI,
+// e.g. from an Update callback.
+func (g *Gui) ScreenBuffer() [][]rune {
+	rows := make([][]rune, g.maxY)
+	for y := range rows {
+		rows[y] = make([]rune, g.maxX)
+		for x := range rows[y] {
+			rows[y][x], _, _, _ = screen.GetContent(x, y)
+		}
+	}
+	return rows
+}
+
 // SetView creates a new view with its top-left corner at (x0, y0)
 // and the bottom-right one at (x1, y1). If a view with the same name
 // already exists, its dimensions are updated; otherwise, the error
@@ -283,7 +397,8 @@ func (g *Gui) ViewByPosition(x, y int) (*View, error) {
 	// traverse views in reverse order checking top views first
 	for i := len(g.views); i > 0; i-- {
 		v := g.views[i-1]
-		if x > v.x0 && x < v.x1 && y > v.y0 && y < v.y1 {
+		cx, cy, width, height := v.contentArea()
+		if x >= cx && x < cx+width && y >= cy && y < cy+height {
 			return v, nil
 		}
 	}
@@ -312,11 +427,16 @@ func (g *Gui) DeleteView(name string) error {
 	return ErrUnknownView
 }
 
-// SetCurrentView gives the focus to a given view.
+// SetCurrentView gives the focus to a given view. OnFocusChange is called
+// if the view didn't have the focus.
 func (g *Gui) SetCurrentView(name string) (*View, error) {
 	for _, v := range g.views {
 		if v.name == name {
+			prev := g.currentView
 			g.currentView = v
+			if g.OnFocusChange != nil && prev != v {
+				g.OnFocusChange(g, prev, v)
+			}
 			return v, nil
 		}
 	}
@@ -349,7 +469,70 @@ func (g *Gui) SetKeybinding(viewname string, key interface{}, mod Modifier, hand
 		return ErrBlacklisted
 	}
 
-	kb = newKeybinding(viewname, k, ch, mod, handler)
+	kb = newKeybinding(viewname, k, ch, mod, simpleHandler(handler))
+	g.keybindings = append(g.keybindings, kb)
+	return nil
+}
+
+// SetKeybindingFull works like SetKeybinding, but the handler is passed the
+// key, the rune and the modifier of the key press that triggered it. It
+// allows to share a handler between several keys.
+func (g *Gui) SetKeybindingFull(viewname string, key interface{}, mod Modifier, handler func(*Gui, *View, Key, rune, Modifier) error) error {
+	k, ch, err := getKey(key)
+	if err != nil {
+		return err
+	}
+
+	if g.isBlacklisted(k) {
+		return ErrBlacklisted
+	}
+
+	g.keybindings = append(g.keybindings, newKeybinding(viewname, k, ch, mod, handler))
+	return nil
+}
+
+// SetKeybindingSequence creates a keybinding that is triggered by typing the
+// given keys one after the other, e.g. []interface{}{'g', 'g'}. The keys
+// are Keys or runes, mod applies to all of them. The sequence is reset if
+// the delay between two keys is longer than KeySequenceTimeout.
+//
+// While the typed keys may start a sequence they are held back, they go
+// through the other keybindings once it's clear that they don't, because of
+// a key that doesn't continue the sequence or because of the timeout.
+func (g *Gui) SetKeybindingSequence(viewname string, keys []interface{}, mod Modifier, handler func(*Gui, *View) error) error {
+	if len(keys) == 0 {
+		return errors.New("empty key sequence")
+	}
+
+	seq := make([]keyPress, 0, len(keys))
+	for _, key := range keys {
+		k, ch, err := getKey(key)
+		if err != nil {
+			return err
+		}
+		if g.isBlacklisted(k) {
+			return ErrBlacklisted
+		}
+		seq = append(seq, keyPress{key: k, ch: ch, mod: mod})
+	}
+
+	kb := newKeybinding(viewname, seq[0].key, seq[0].ch, mod, simpleHandler(handler))
+	kb.keys = seq
+	g.keybindings = append(g.keybindings, kb)
+	return nil
+}
+
+// SetKeybindingFunc creates a keybinding that is triggered by the keys for
+// which match returns true, e.g. to handle any digit with one handler. It's
+// only consulted if no other keybinding matches the key, exact keybindings
+// always take precedence.
+func (g *Gui) SetKeybindingFunc(viewname string, match func(Key, rune, Modifier) bool, handler func(*Gui, *View) error) error {
+	if match == nil {
+		return errors.New("nil match func")
+	}
+
+	kb := newKeybinding(viewname, 0, 0, ModNone, simpleHandler(handler))
+	kb.match = match
 	g.keybindings = append(g.keybindings, kb)
 	return nil
 }
@@ -362,7 +545,7 @@ func (g *Gui) DeleteKeybinding(viewname string, key interface{}, mod Modifier) e
 	}
 
 	for i, kb := range g.keybindings {
-		if kb.viewName == viewname && kb.ch == ch && kb.key == k && kb.mod == mod {
+		if kb.keys == nil && kb.match == nil && kb.viewName == viewname && kb.ch == ch && kb.key == k && kb.mod == mod {
 			g.keybindings = append(g.keybindings[:i], g.keybindings[i+1:]...)
 			return nil
 		}
@@ -370,6 +553,28 @@ func (g *Gui) DeleteKeybinding(viewname string, key interface{}, mod Modifier) e
 	return errors.New("keybinding not found")
 }
 
+// SetKeybindingEnabled enables or disables a keybinding. A disabled
+// keybinding is ignored, the key is handled as if it wasn't bound, until
+// it's enabled again.
+func (g *Gui) SetKeybindingEnabled(viewname string, key interface{}, mod Modifier, enabled bool) error {
+	k, ch, err := getKey(key)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, kb := range g.keybindings {
+		if kb.keys == nil && kb.match == nil && kb.viewName == viewname && kb.ch == ch && kb.key == k && kb.mod == mod {
+			kb.disabled = !enabled
+			found = true
+		}
+	}
+	if !found {
+		return errors.New("keybinding not found")
+	}
+	return nil
+}
+
 // DeleteKeybindings deletes all keybindings of view.
 func (g *Gui) DeleteKeybindings(viewname string) {
 	var s []*keybinding
@@ -430,6 +635,21 @@ func (g *Gui) Update(f func(*Gui) error) {
 	go g.UpdateAsync(f)
 }
 
+// Batch calls fn and doesn't redraw the GUI until it returns, e.g. to append
+// many lines to a view with Update without redrawing it for each one. If
+// MainLoop is running, the GUI is redrawn once fn returns, even if it fails.
+// Batch can be called from any goroutine and batches can be nested.
+func (g *Gui) Batch(fn func() error) error {
+	atomic.AddInt32(&g.batchDepth, 1)
+	defer func() {
+		// Without a main loop, the next one redraws the GUI
+		if atomic.AddInt32(&g.batchDepth, -1) == 0 && atomic.LoadInt32(&g.running) == 1 {
+			g.Update(func(*Gui) error { return nil })
+		}
+	}()
+	return fn()
+}
+
 // UpdateAsync is a version of Update that does not spawn a go routine, it can
 // be a bit more efficient in cases where Update is called many times like when
 // tailing a file.  In general you should use Update()
@@ -474,18 +694,28 @@ func (g *Gui) SetManagerFunc(manager func(*Gui) error) {
 // MainLoop runs the main loop until an error is returned. A successful
 // finish should return ErrQuit.
 func (g *Gui) MainLoop() error {
+	atomic.StoreInt32(&g.running, 1)
+	defer atomic.StoreInt32(&g.running, 0)
+
 	g.loaderTick()
 	if err := g.flush(); err != nil {
 		return err
 	}
 
+	g.loops.Add(1)
 	go func() {
+		defer g.loops.Done()
 		for {
 			select {
-			case <-g.stop:
+			case <-g.closed:
 				return
 			default:
-				g.gEvents <- pollEvent()
+			}
+			ev := pollEvent()
+			select {
+			case g.gEvents <- ev:
+			case <-g.closed:
+				return
 			}
 		}
 	}()
@@ -493,6 +723,9 @@ func (g *Gui) MainLoop() error {
 	if g.Mouse {
 		screen.EnableMouse()
 	}
+	if g.Paste {
+		screen.EnablePaste()
+	}
 
 	if err := g.flush(); err != nil {
 		return err
@@ -510,13 +743,17 @@ func (g *Gui) MainLoop() error {
 			}
 		case <-g.stop:
 			return nil
+		case <-g.closed:
+			return nil
 		}
 
 		if err := g.consumeevents(); err != nil {
 			return err
 		}
-		if err := g.flush(); err != nil {
-			return err
+		if atomic.LoadInt32(&g.batchDepth) == 0 {
+			if err := g.flush(); err != nil {
+				return err
+			}
 		}
 		// used during testing for synchronization
 		if g.testNotify != nil && g.testCounter > 0 {
@@ -551,7 +788,18 @@ func (g *Gui) consumeevents() error {
 func (g *Gui) handleEvent(ev *gocuiEvent) error {
 	switch ev.Type {
 	case eventKey, eventMouse:
+		if g.pasting {
+			g.pasteKey(ev)
+			return nil
+		}
 		return g.onKey(ev)
+	case eventPasteStart:
+		g.pasting, g.pasted = true, nil
+		return nil
+	case eventPasteEnd:
+		g.pasting = false
+		g.onPaste(string(g.pasted))
+		return nil
 	case eventTime:
 		g.testCounter++
 		return nil
@@ -575,20 +823,33 @@ func (g *Gui) flush() error {
 		for _, v := range g.views {
 			v.tainted = true
 		}
+		g.resized = false
 	}
 	g.maxX, g.maxY = maxX, maxY
+	if g.OnResize != nil && !g.resized {
+		g.resized = true
+		g.OnResize(g, maxX, maxY)
+	}
 
 	for _, m := range g.managers {
 		if err := m.Layout(g); err != nil {
 			return err
 		}
 	}
+	hasLoader := int32(0)
+	for _, v := range g.views {
+		if v.HasLoader {
+			hasLoader = 1
+			break
+		}
+	}
+	atomic.StoreInt32(&g.hasLoader, hasLoader)
 	for _, v := range g.views {
 		if !v.Visible || v.y1 < v.y0 {
 			continue
 		}
+		var fgColor, bgColor, frameColor Attribute
 		if v.Frame {
-			var fgColor, bgColor, frameColor Attribute
 			if g.Highlight && v == g.currentView {
 				fgColor = g.SelFgColor
 				bgColor = g.SelBgColor
@@ -606,6 +867,12 @@ func (g *Gui) flush() error {
 					frameColor = g.FrameColor
 				}
 			}
+			if v.FrameBgColor != ColorDefault {
+				bgColor = v.FrameBgColor
+			}
+			if v == g.currentView && v.FocusedFrameFgColor != ColorDefault {
+				frameColor = v.FocusedFrameFgColor
+			}
 
 			if err := g.drawFrameEdges(v, frameColor, bgColor); err != nil {
 				return err
@@ -613,20 +880,31 @@ func (g *Gui) flush() error {
 			if err := g.drawFrameCorners(v, frameColor, bgColor); err != nil {
 				return err
 			}
-			if v.Title != "" {
+			if v.Title != "" && v.FrameSides.Top {
 				if err := g.drawTitle(v, fgColor, bgColor); err != nil {
 					return err
 				}
 			}
-			if v.Subtitle != "" {
+			if v.Subtitle != "" && v.FrameSides.Top {
 				if err := g.drawSubtitle(v, fgColor, bgColor); err != nil {
 					return err
 				}
 			}
+			if v.Footer != "" && v.FrameSides.Bottom && v.y1 > v.y0 {
+				if err := g.drawFooter(v, fgColor, bgColor); err != nil {
+					return err
+				}
+			}
 		}
 		if err := g.draw(v); err != nil {
 			return err
 		}
+		// The thumb is computed when the content is drawn
+		if v.Frame && v.ShowScrollbar && v.FrameSides.Right {
+			if err := g.drawScrollbar(v, frameColor, bgColor); err != nil {
+				return err
+			}
+		}
 	}
 	screen.Show()
 	return nil
@@ -643,25 +921,34 @@ func (g *Gui) clear(fg, bg Attribute) (int, int) {
 	return w, h
 }
 
-// drawFrameEdges draws the horizontal and vertical edges of a view.
-func (g *Gui) drawFrameEdges(v *View, fgColor, bgColor Attribute) error {
-	runeH, runeV := '─', '│'
+// edgeRunes returns the runes of the horizontal and vertical edges of the
+// frame of a view.
+func (g *Gui) edgeRunes(v *View) (runeH, runeV rune) {
+	runeH, runeV = '─', '│'
 	if g.ASCII {
 		runeH, runeV = '-', '|'
 	} else if len(v.FrameRunes) >= 2 {
 		runeH, runeV = v.FrameRunes[0], v.FrameRunes[1]
 	}
+	return runeH, runeV
+}
+
+// drawFrameEdges draws the horizontal and vertical edges of a view that are
+// turned on in FrameSides.
+func (g *Gui) drawFrameEdges(v *View, fgColor, bgColor Attribute) error {
+	runeH, runeV := g.edgeRunes(v)
+	sides := v.FrameSides
 
 	for x := v.x0 + 1; x < v.x1 && x < g.maxX; x++ {
 		if x < 0 {
 			continue
 		}
-		if v.y0 > -1 && v.y0 < g.maxY {
+		if sides.Top && v.y0 > -1 && v.y0 < g.maxY {
 			if err := g.SetRune(x, v.y0, runeH, fgColor, bgColor); err != nil {
 				return err
 			}
 		}
-		if v.y1 > -1 && v.y1 < g.maxY {
+		if sides.Bottom && v.y1 > -1 && v.y1 < g.maxY {
 			if err := g.SetRune(x, v.y1, runeH, fgColor, bgColor); err != nil {
 				return err
 			}
@@ -671,12 +958,12 @@ func (g *Gui) drawFrameEdges(v *View, fgColor, bgColor Attribute) error {
 		if y < 0 {
 			continue
 		}
-		if v.x0 > -1 && v.x0 < g.maxX {
+		if sides.Left && v.x0 > -1 && v.x0 < g.maxX {
 			if err := g.SetRune(v.x0, y, runeV, fgColor, bgColor); err != nil {
 				return err
 			}
 		}
-		if v.x1 > -1 && v.x1 < g.maxX {
+		if sides.Right && v.x1 > -1 && v.x1 < g.maxX {
 			if err := g.SetRune(v.x1, y, runeV, fgColor, bgColor); err != nil {
 				return err
 			}
@@ -685,6 +972,30 @@ func (g *Gui) drawFrameEdges(v *View, fgColor, bgColor Attribute) error {
 	return nil
 }
 
+// drawScrollbar draws the thumb of the scrollbar of a view on its right
+// edge, if its content doesn't fit in its height.
+func (g *Gui) drawScrollbar(v *View, fgColor, bgColor Attribute) error {
+	start, size := v.thumbStart, v.thumbSize
+	if size == 0 || v.x1 < 0 || v.x1 >= g.maxX {
+		return nil
+	}
+	runeThumb := '█'
+	if g.ASCII {
+		runeThumb = '#'
+	}
+
+	_, cy, _, _ := v.contentArea()
+	for y := cy + start; y < cy+start+size && y < g.maxY; y++ {
+		if y < 0 {
+			continue
+		}
+		if err := g.SetRune(v.x1, y, runeThumb, fgColor, bgColor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func cornerRune(index byte) rune {
 	return []rune{' ', '│', '│', '│', '─', '┘', '┐', '┤', '─', '└', '┌', '├', '├', '┴', '┬', '┼'}[index]
 }
@@ -778,13 +1089,33 @@ func (g *Gui) drawFrameCorners(v *View, fgColor, bgColor Attribute) error {
 		runeTL, runeTR, runeBL, runeBR = '+', '+', '+', '+'
 	}
 
+	// A corner between a side that is turned on and one that is turned off
+	// continues the edge of the first one
+	runeH, runeV := g.edgeRunes(v)
+	sides := v.FrameSides
+	cornerOrEdge := func(ch rune, horizontal, vertical bool) rune {
+		switch {
+		case horizontal && vertical:
+			return ch
+		case horizontal:
+			return runeH
+		case vertical:
+			return runeV
+		}
+		return 0
+	}
 	corners := []struct {
 		x, y int
 		ch   rune
-	}{{v.x0, v.y0, runeTL}, {v.x1, v.y0, runeTR}, {v.x0, v.y1, runeBL}, {v.x1, v.y1, runeBR}}
+	}{
+		{v.x0, v.y0, cornerOrEdge(runeTL, sides.Top, sides.Left)},
+		{v.x1, v.y0, cornerOrEdge(runeTR, sides.Top, sides.Right)},
+		{v.x0, v.y1, cornerOrEdge(runeBL, sides.Bottom, sides.Left)},
+		{v.x1, v.y1, cornerOrEdge(runeBR, sides.Bottom, sides.Right)},
+	}
 
 	for _, c := range corners {
-		if c.x >= 0 && c.y >= 0 && c.x < g.maxX && c.y < g.maxY {
+		if c.ch != 0 && c.x >= 0 && c.y >= 0 && c.x < g.maxX && c.y < g.maxY {
 			if err := g.SetRune(c.x, c.y, c.ch, fgColor, bgColor); err != nil {
 				return err
 			}
@@ -795,44 +1126,106 @@ func (g *Gui) drawFrameCorners(v *View, fgColor, bgColor Attribute) error {
 
 // drawTitle draws the title of the view.
 func (g *Gui) drawTitle(v *View, fgColor, bgColor Attribute) error {
-	if v.y0 < 0 || v.y0 >= g.maxY {
+	x, title, _, _ := v.titleLayout()
+	return g.drawFrameText(x, v.y0, title, fgColor, bgColor)
+}
+
+// drawSubtitle draws the subtitle of the view.
+func (g *Gui) drawSubtitle(v *View, fgColor, bgColor Attribute) error {
+	_, _, x, subtitle := v.titleLayout()
+	return g.drawFrameText(x, v.y0, subtitle, fgColor, bgColor)
+}
+
+// drawFooter draws the footer of the view in its bottom border, aligned
+// according to FooterAlignment and truncated if it doesn't fit.
+func (g *Gui) drawFooter(v *View, fgColor, bgColor Attribute) error {
+	start, width := v.x0+2, v.x1-v.x0-3
+	if width <= 0 {
 		return nil
 	}
 
-	for i, ch := range v.Title {
-		x := v.x0 + i + 2
-		if x < 0 {
+	footer := []rune(v.Footer)
+	if len(footer) > width {
+		footer = footer[:width]
+	}
+	x := start
+	switch v.FooterAlignment {
+	case AlignCenter:
+		x += (width - len(footer)) / 2
+	case AlignRight:
+		x += width - len(footer)
+	}
+	return g.drawFrameText(x, v.y1, footer, fgColor, bgColor)
+}
+
+// drawFrameText draws the given runes on the row y of the screen, starting
+// at the column x. The runes outside of the screen aren't drawn.
+func (g *Gui) drawFrameText(x, y int, text []rune, fgColor, bgColor Attribute) error {
+	if y < 0 || y >= g.maxY {
+		return nil
+	}
+
+	for i, ch := range text {
+		if x+i < 0 {
 			continue
-		} else if x > v.x1-2 || x >= g.maxX {
+		} else if x+i >= g.maxX {
 			break
 		}
-		if err := g.SetRune(x, v.y0, ch, fgColor, bgColor); err != nil {
+		if err := g.SetRune(x+i, y, ch, fgColor, bgColor); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-// drawSubtitle draws the subtitle of the view.
-func (g *Gui) drawSubtitle(v *View, fgColor, bgColor Attribute) error {
-	if v.y0 < 0 || v.y0 >= g.maxY {
-		return nil
+// titleLayout returns the runes of the title and of the subtitle of the view
+// that fit in its top border, and the columns they start at. The title is
+// aligned according to TitleAlignment and the subtitle is drawn at the
+// opposite end of the border, at its right end unless the title is aligned
+// to the right. The title takes precedence: the subtitle is truncated first
+// when the border is too narrow for both.
+func (v *View) titleLayout() (titleX int, title []rune, subtitleX int, subtitle []rune) {
+	// The texts are drawn between the first and the last edge runes
+	start, width := v.x0+2, v.x1-v.x0-3
+	if width <= 0 {
+		return 0, nil, 0, nil
 	}
 
-	start := v.x1 - 5 - len(v.Subtitle)
-	if start < v.x0 {
-		return nil
+	title = []rune(v.Title)
+	if len(title) > width {
+		title = title[:width]
 	}
-	for i, ch := range v.Subtitle {
-		x := start + i
-		if x >= v.x1 {
-			break
-		}
-		if err := g.SetRune(x, v.y0, ch, fgColor, bgColor); err != nil {
-			return err
+	room := width
+	if len(title) > 0 {
+		room -= len(title) + 1
+	}
+	if v.TitleAlignment != AlignRight {
+		// Some more edge runes are kept after a subtitle on the right
+		room -= 4
+	}
+	subtitle = []rune(v.Subtitle)
+	if room < 0 {
+		room = 0
+	}
+	if len(subtitle) > room {
+		subtitle = subtitle[:room]
+	}
+
+	switch v.TitleAlignment {
+	case AlignRight:
+		titleX = start + width - len(title)
+		subtitleX = start
+	case AlignCenter:
+		subtitleX = v.x1 - 5 - len(subtitle)
+		titleX = start + (width-len(title))/2
+		if len(subtitle) > 0 && titleX+len(title) >= subtitleX {
+			titleX = subtitleX - 1 - len(title)
 		}
+	default:
+		titleX = start
+		subtitleX = v.x1 - 5 - len(subtitle)
 	}
-	return nil
+	return titleX, title, subtitleX, subtitle
 }
 
 // draw manages the cursor and calls the draw function of a view.
@@ -866,38 +1259,82 @@ func (g *Gui) draw(v *View) error {
 		return completed(true)
 	}
 
-	x := curview.x0 + cursorX + 1 - curview.ox
-	y := curview.y0 + cursorY + 1 - curview.oy
+	cx, cy, _, _ := curview.contentArea()
+	x := cx + cursorX - curview.ox + curview.gutterWidth()
+	y := cy + cursorY - curview.oy
 	screen.ShowCursor(x, y)
 
 	return completed(false)
 }
 
+// pasteKey adds the rune of a key pressed during a bracketed paste to the
+// pasted text. The other keys and the mouse events are ignored.
+func (g *Gui) pasteKey(ev *gocuiEvent) {
+	if ev.Type != eventKey {
+		return
+	}
+	switch {
+	case ev.Ch != 0:
+		g.pasted = append(g.pasted, ev.Ch)
+	case ev.Key == KeySpace:
+		g.pasted = append(g.pasted, ' ')
+	case ev.Key == KeyTab:
+		g.pasted = append(g.pasted, '\t')
+	case ev.Key == KeyEnter || ev.Key == KeyCtrlJ:
+		g.pasted = append(g.pasted, '\n')
+	}
+}
+
+// onPaste gives the text of a bracketed paste to the current view.
+func (g *Gui) onPaste(text string) {
+	v := g.currentView
+	switch {
+	case v == nil || text == "":
+	case v.OnPaste != nil:
+		v.OnPaste(v, text)
+	case v.Editable:
+		v.pasteString(text)
+	}
+}
+
 // onKey manages key-press events. A keybinding handler is called when
 // a key-press or mouse event satisfies a configured keybinding. Furthermore,
 // currentView's internal buffer is modified if currentView.Editable is true.
 func (g *Gui) onKey(ev *gocuiEvent) error {
 	switch ev.Type {
 	case eventKey:
-		matched, err := g.execKeybindings(g.currentView, ev)
-		if err != nil {
-			return err
-		}
-		if matched {
-			break
-		}
-		if g.currentView != nil && g.currentView.Editable && g.currentView.Editor != nil {
-			g.currentView.Editor.Edit(g.currentView, Key(ev.Key), ev.Ch, Modifier(ev.Mod))
-		}
+		return g.dispatchKey(g.currentView, ev)
 	case eventMouse:
 		mx, my := ev.MouseX, ev.MouseY
 		g.mouseX = mx
 		g.mouseY = my
+		// Motion with a button held extends the selection of the view
+		// where the drag started, even outside of it
+		if ev.Key == 0 && g.dragView != nil {
+			g.dragView.dragTo(mx, my)
+			break
+		}
+		if ev.Key == MouseRelease {
+			g.dragView = nil
+		}
 		v, err := g.ViewByPosition(mx, my)
 		if err != nil {
 			break
 		}
-		if err := v.SetCursor(mx-v.x0-1+v.ox, my-v.y0-1+v.oy); err != nil {
+		// Clicks on the frame don't move the cursor
+		if ev.Key == MouseLeft {
+			if err := v.startDrag(mx, my); err == nil {
+				g.dragView = v
+				switch g.countClick(mx, my, time.Now()) {
+				case 2:
+					v.selectWord()
+				case 3:
+					v.selectLine()
+				}
+			} else if err != ErrInvalidPoint {
+				return err
+			}
+		} else if err := v.SetCursorFromScreen(mx, my); err != nil && err != ErrInvalidPoint {
 			return err
 		}
 		if _, err := g.execKeybindings(v, ev); err != nil {
@@ -908,13 +1345,165 @@ func (g *Gui) onKey(ev *gocuiEvent) error {
 	return nil
 }
 
+// countClick registers a left click at the point (x, y) of the screen at
+// the given time and returns the number of consecutive clicks it ends, up
+// to a triple click.
+func (g *Gui) countClick(x, y int, now time.Time) int {
+	if g.clicks > 0 && g.clicks < 3 && x == g.clickX && y == g.clickY &&
+		now.Sub(g.clickTime) <= g.DoubleClickInterval {
+		g.clicks++
+	} else {
+		g.clicks = 1
+	}
+	g.clickTime, g.clickX, g.clickY = now, x, y
+	return g.clicks
+}
+
+// dispatchKey handles the key event for the view v. Keys that may start a
+// keybinding sequence are held back until the sequence is complete, the
+// other ones are handled by dispatchSingleKey.
+func (g *Gui) dispatchKey(v *View, ev *gocuiEvent) error {
+	if len(g.pendingKeys) > 0 && (v != g.pendingView || time.Since(g.pendingTime) > g.KeySequenceTimeout) {
+		if err := g.flushPendingKeys(); err != nil {
+			return err
+		}
+	}
+
+	keys := append(append([]keyPress(nil), g.pendingKeys...), keyPress{key: ev.Key, ch: ev.Ch, mod: ev.Mod})
+	kb, prefix := g.matchSequence(v, keys)
+	switch {
+	case kb != nil:
+		g.pendingKeys = nil
+		g.stopPendingTimer()
+		_, err := g.execKeybinding(v, kb, ev)
+		return err
+	case prefix:
+		g.pendingKeys, g.pendingView, g.pendingTime = keys, v, time.Now()
+		g.pendingGen++
+		gen := g.pendingGen
+		g.stopPendingTimer()
+		g.pendingTimer = time.AfterFunc(g.KeySequenceTimeout, func() {
+			// Without a main loop, the keys are flushed by the next one
+			if atomic.LoadInt32(&g.running) == 0 {
+				return
+			}
+			g.Update(func(g *Gui) error {
+				if gen != g.pendingGen {
+					return nil
+				}
+				return g.flushPendingKeys()
+			})
+		})
+		return nil
+	case len(g.pendingKeys) > 0:
+		// The held back keys don't start a sequence with this one
+		if err := g.flushPendingKeys(); err != nil {
+			return err
+		}
+		return g.dispatchKey(v, ev)
+	}
+	return g.dispatchSingleKey(v, ev)
+}
+
+// flushPendingKeys handles the keys held back by dispatchKey one by one.
+func (g *Gui) flushPendingKeys() error {
+	keys, v := g.pendingKeys, g.pendingView
+	g.pendingKeys, g.pendingView = nil, nil
+	g.stopPendingTimer()
+	for _, k := range keys {
+		if err := g.dispatchSingleKey(v, &gocuiEvent{Type: eventKey, Key: k.key, Ch: k.ch, Mod: k.mod}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopPendingTimer stops the timer that flushes the keys held back by
+// dispatchKey.
+func (g *Gui) stopPendingTimer() {
+	if g.pendingTimer != nil {
+		g.pendingTimer.Stop()
+		g.pendingTimer = nil
+	}
+}
+
+// matchSequence returns the keybinding sequence matching the keys typed in
+// the view v, view keybindings take precedence over global ones. If there
+// is no match, prefix reports whether the keys start a sequence.
+func (g *Gui) matchSequence(v *View, keys []keyPress) (match *keybinding, prefix bool) {
+	var globalKb *keybinding
+	for _, kb := range g.keybindings {
+		if kb.handler == nil || !kb.matchSequence(keys) {
+			continue
+		}
+		viewKb := kb.matchView(v)
+		if !viewKb && !(kb.viewName == "" && (v == nil || !v.Editable || kb.ch == 0)) {
+			continue
+		}
+
+		switch {
+		case len(kb.keys) > len(keys):
+			prefix = true
+		case viewKb:
+			return kb, false
+		default:
+			globalKb = kb
+		}
+	}
+	if globalKb != nil {
+		return globalKb, false
+	}
+	return nil, prefix
+}
+
+// dispatchSingleKey executes the keybinding handlers matching the key event
+// for the view v, keys that don't match any keybinding are passed to the
+// editor of v if it's editable. With View.EditorFallthrough it's the other
+// way around.
+func (g *Gui) dispatchSingleKey(v *View, ev *gocuiEvent) error {
+	if v != nil && v.Editable && v.EditorFallthrough {
+		if e, ok := v.Editor.(FallthroughEditor); ok {
+			if e.HandleKey(v, Key(ev.Key), ev.Ch, Modifier(ev.Mod)) {
+				return nil
+			}
+			_, err := g.execKeybindings(v, ev)
+			return err
+		}
+	}
+
+	matched, err := g.execKeybindings(v, ev)
+	if err != nil {
+		return err
+	}
+	if !matched && v != nil && v.Editable && v.Editor != nil {
+		v.Editor.Edit(v, Key(ev.Key), ev.Ch, Modifier(ev.Mod))
+	}
+	return nil
+}
+
+// SimulateKeypress handles a key press as if it was typed in the view with
+// the given name, or in the current view if the name is empty. The matching
+// keybinding handler, or the editor of the view, is called before it
+// returns. It allows to test the keybindings of an application without
+// running MainLoop, the view doesn't need to be the current one.
+func (g *Gui) SimulateKeypress(viewName string, key Key, ch rune, mod Modifier) error {
+	v := g.currentView
+	if viewName != "" {
+		var err error
+		if v, err = g.View(viewName); err != nil {
+			return err
+		}
+	}
+	return g.dispatchKey(v, &gocuiEvent{Type: eventKey, Key: key, Ch: ch, Mod: mod})
+}
+
 // execKeybindings executes the keybinding handlers that match the passed view
 // and event. The value of matched is true if there is a match and no errors.
 func (g *Gui) execKeybindings(v *View, ev *gocuiEvent) (matched bool, err error) {
 	var globalKb *keybinding
 
 	for _, kb := range g.keybindings {
-		if kb.handler == nil {
+		if kb.handler == nil || kb.disabled || kb.keys != nil || kb.match != nil {
 			continue
 		}
 
@@ -923,7 +1512,7 @@ func (g *Gui) execKeybindings(v *View, ev *gocuiEvent) (matched bool, err error)
 		}
 
 		if kb.matchView(v) {
-			return g.execKeybinding(v, kb)
+			return g.execKeybinding(v, kb, ev)
 		}
 
 		if kb.viewName == "" && (((v != nil && !v.Editable) || kb.ch == 0) || v == nil) {
@@ -932,19 +1521,48 @@ func (g *Gui) execKeybindings(v *View, ev *gocuiEvent) (matched bool, err error)
 	}
 
 	if globalKb != nil {
-		return g.execKeybinding(v, globalKb)
+		return g.execKeybinding(v, globalKb, ev)
 	}
 
-	return false, nil
+	return g.execKeybindingFuncs(v, ev)
+}
+
+// execKeybindingFuncs executes the first keybinding created with
+// SetKeybindingFunc that matches the passed view and event, view
+// keybindings take precedence over global ones.
+func (g *Gui) execKeybindingFuncs(v *View, ev *gocuiEvent) (matched bool, err error) {
+	var found *keybinding
+	key, ch, mod := Key(ev.Key), ev.Ch, Modifier(ev.Mod)
+
+	for _, kb := range g.keybindings {
+		if kb.handler == nil || kb.match == nil || !kb.match(key, ch, mod) {
+			continue
+		}
+
+		// Like for runes, ignore the view keybinding if the user is typing
+		if v != nil && kb.viewName == v.name && (!v.Editable || ch == 0 || v.KeybindOnEdit) {
+			found = kb
+			break
+		}
+
+		if found == nil && kb.viewName == "" && (v == nil || !v.Editable || ch == 0) {
+			found = kb
+		}
+	}
+
+	if found == nil {
+		return false, nil
+	}
+	return g.execKeybinding(v, found, ev)
 }
 
-// execKeybinding executes a given keybinding
-func (g *Gui) execKeybinding(v *View, kb *keybinding) (bool, error) {
-	if g.isBlacklisted(kb.key) {
+// execKeybinding executes a given keybinding for the passed event
+func (g *Gui) execKeybinding(v *View, kb *keybinding, ev *gocuiEvent) (bool, error) {
+	if g.isBlacklisted(Key(ev.Key)) {
 		return true, nil
 	}
 
-	if err := kb.handler(g, v); err != nil {
+	if err := kb.handler(g, v, Key(ev.Key), ev.Ch, Modifier(ev.Mod)); err != nil {
 		return false, err
 	}
 	return true, nil
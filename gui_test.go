@@ -0,0 +1,929 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// newTestGui returns a Gui using the simulated screen with an editable
+// current view named "main".
+func newTestGui(t *testing.T) (*Gui, *View) {
+	t.Helper()
+	g, err := NewGui(OutputSimulator, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+	v, err := g.SetView("main", 0, 0, 20, 5, 0)
+	if !errors.Is(err, ErrUnknownView) {
+		t.Fatal(err)
+	}
+	v.Editable = true
+	if _, err := g.SetCurrentView("main"); err != nil {
+		t.Fatal(err)
+	}
+	return g, v
+}
+
+// typeKeys sends the runes of s to the current view with SimulateKeypress.
+func typeKeys(t *testing.T, g *Gui, s string) {
+	t.Helper()
+	for _, r := range s {
+		if err := g.SimulateKeypress("", 0, r, ModNone); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestKeybindingSequence(t *testing.T) {
+	g, v := newTestGui(t)
+	v.Editable = false
+
+	var calls []string
+	record := func(name string) func(*Gui, *View) error {
+		return func(*Gui, *View) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	if err := g.SetKeybindingSequence("main", []interface{}{'g', 'g'}, ModNone, record("gg")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetKeybindingSequence("", []interface{}{'d', KeyCtrlD}, ModNone, record("d^D")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetKeybinding("main", 'g', ModNone, record("g")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetKeybinding("", 'x', ModNone, record("x")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		keys string
+		want string
+	}{
+		{"sequence", "gg", "gg"},
+		{"sequence twice", "gggg", "gg,gg"},
+		{"conflicting single key", "gx", "g,x"},
+		{"held back key", "g", ""},
+		{"prefix of an unbound sequence", "dx", "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			g.pendingKeys = nil
+			typeKeys(t, g, tt.keys)
+			if got := strings.Join(calls, ","); got != tt.want {
+				t.Errorf("Expected handler calls %q got: %q", tt.want, got)
+			}
+		})
+	}
+
+	calls = nil
+	typeKeys(t, g, "d")
+	if err := g.SimulateKeypress("", KeyCtrlD, 0, ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(calls, ","); got != "d^D" {
+		t.Errorf("Expected handler calls %q got: %q", "d^D", got)
+	}
+
+	// A partial sequence is reset after the timeout
+	calls = nil
+	g.KeySequenceTimeout = 10 * time.Millisecond
+	typeKeys(t, g, "g")
+	time.Sleep(30 * time.Millisecond)
+	typeKeys(t, g, "g")
+	if got := strings.Join(calls, ","); got != "g" {
+		t.Errorf("Expected handler calls %q after the timeout got: %q", "g", got)
+	}
+	if len(g.pendingKeys) != 1 {
+		t.Errorf("Expected the last key to be held back got: %d keys", len(g.pendingKeys))
+	}
+
+	// Without a main loop, the timer doesn't post an update nobody handles
+	if n := len(g.userEvents); n != 0 {
+		t.Errorf("Expected no pending update got: %d", n)
+	}
+	typeKeys(t, g, "g")
+	if g.pendingTimer != nil {
+		t.Error("Expected the timer to be stopped once the sequence is complete")
+	}
+}
+
+func TestKeybindingSequenceEditor(t *testing.T) {
+	g, v := newTestGui(t)
+	called := false
+	if err := g.SetKeybindingSequence("", []interface{}{KeyCtrlX, KeyCtrlS}, ModNone, func(*Gui, *View) error {
+		called = true
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Runes aren't held back in an editable view if the sequence isn't
+	// bound to the view
+	typeKeys(t, g, "ab")
+	if err := g.SimulateKeypress("", KeyCtrlX, 0, ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SimulateKeypress("", KeyCtrlS, 0, ModNone); err != nil {
+		t.Fatal(err)
+	}
+	typeKeys(t, g, "c")
+	if !called {
+		t.Error("Expected the sequence handler to be called")
+	}
+	if got := v.Buffer(); got != "abc" {
+		t.Errorf("Expected the editor to write %q got: %q", "abc", got)
+	}
+}
+
+func TestKeybindingFunc(t *testing.T) {
+	g, v := newTestGui(t)
+	v.Editable = false
+
+	var calls []string
+	record := func(name string) func(*Gui, *View) error {
+		return func(*Gui, *View) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+	isDigit := func(key Key, ch rune, mod Modifier) bool {
+		return ch >= '0' && ch <= '9'
+	}
+	isRune := func(key Key, ch rune, mod Modifier) bool {
+		return ch != 0
+	}
+	if err := g.SetKeybindingFunc("", isRune, record("rune")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetKeybindingFunc("main", isDigit, record("digit")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetKeybinding("", '0', ModNone, record("zero")); err != nil {
+		t.Fatal(err)
+	}
+
+	typeKeys(t, g, "05a")
+	if got, want := strings.Join(calls, ","), "zero,digit,rune"; got != want {
+		t.Errorf("Expected handler calls %q got: %q", want, got)
+	}
+
+	// The predicates don't catch the keys typed in an editable view
+	calls = nil
+	v.Editable = true
+	typeKeys(t, g, "5a")
+	if len(calls) != 0 {
+		t.Errorf("Expected no handler calls in an editable view got: %q", calls)
+	}
+	if got := v.Buffer(); got != "5a" {
+		t.Errorf("Expected the editor to write %q got: %q", "5a", got)
+	}
+}
+
+func TestSetKeybindingEnabled(t *testing.T) {
+	g, v := newTestGui(t)
+	calls := 0
+	if err := g.SetKeybinding("main", KeyCtrlA, ModNone, func(*Gui, *View) error {
+		calls++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetKeybinding("main", 'q', ModNone, func(*Gui, *View) error {
+		calls++
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	v.Editable = false
+
+	press := func() {
+		if err := g.SimulateKeypress("", KeyCtrlA, 0, ModNone); err != nil {
+			t.Fatal(err)
+		}
+	}
+	press()
+	if err := g.SetKeybindingEnabled("main", KeyCtrlA, ModNone, false); err != nil {
+		t.Fatal(err)
+	}
+	press()
+	if calls != 1 {
+		t.Errorf("Expected a disabled keybinding not to fire, got %d calls", calls)
+	}
+	if err := g.SetKeybindingEnabled("main", KeyCtrlA, ModNone, true); err != nil {
+		t.Fatal(err)
+	}
+	press()
+	if calls != 2 {
+		t.Errorf("Expected an enabled keybinding to fire, got %d calls", calls)
+	}
+
+	// A disabled rune keybinding falls through to the editor
+	v.Editable, v.KeybindOnEdit = true, true
+	if err := g.SetKeybindingEnabled("main", 'q', ModNone, false); err != nil {
+		t.Fatal(err)
+	}
+	typeKeys(t, g, "q")
+	if got := v.Buffer(); calls != 2 || got != "q" {
+		t.Errorf("Expected the editor to write %q got: %q (%d calls)", "q", got, calls)
+	}
+
+	if err := g.SetKeybindingEnabled("other", KeyCtrlA, ModNone, true); err == nil {
+		t.Error("Expected an error for a missing keybinding")
+	}
+}
+
+func TestSetKeybindingFull(t *testing.T) {
+	g, v := newTestGui(t)
+	v.Editable = false
+
+	var got []keyPress
+	handler := func(g *Gui, v *View, key Key, ch rune, mod Modifier) error {
+		got = append(got, keyPress{key: key, ch: ch, mod: mod})
+		return nil
+	}
+	if err := g.SetKeybindingFull("main", 'x', ModAlt, handler); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetKeybindingFull("", KeyF2, ModNone, handler); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.SimulateKeypress("", 0, 'x', ModAlt); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SimulateKeypress("", KeyF2, 0, ModNone); err != nil {
+		t.Fatal(err)
+	}
+	want := []keyPress{{ch: 'x', mod: ModAlt}, {key: KeyF2}}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d handler calls got: %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected the handler to see %+v got: %+v", want[i], got[i])
+		}
+	}
+}
+
+// arrowEditor is a FallthroughEditor that only handles the arrow keys.
+type arrowEditor struct{}
+
+func (e arrowEditor) Edit(v *View, key Key, ch rune, mod Modifier) {
+	e.HandleKey(v, key, ch, mod)
+}
+
+func (arrowEditor) HandleKey(v *View, key Key, ch rune, mod Modifier) bool {
+	switch key {
+	case KeyArrowLeft:
+		v.MoveCursor(-1, 0)
+	case KeyArrowRight:
+		v.MoveCursor(1, 0)
+	default:
+		return false
+	}
+	return true
+}
+
+func TestEditorFallthrough(t *testing.T) {
+	g, v := newTestGui(t)
+	v.Editor = arrowEditor{}
+	v.lines = newLineBuffer([][]cell{{{chr: 'a'}, {chr: 'b'}}})
+
+	calls := 0
+	count := func(*Gui, *View) error {
+		calls++
+		return nil
+	}
+	if err := g.SetKeybinding("main", KeyArrowRight, ModNone, count); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetKeybinding("", KeyCtrlS, ModNone, count); err != nil {
+		t.Fatal(err)
+	}
+
+	// By default the keybindings take precedence over the editor
+	if err := g.SimulateKeypress("", KeyArrowRight, 0, ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || v.cx != 0 {
+		t.Errorf("Expected the keybinding to handle the key got: %d calls, cursor x %d", calls, v.cx)
+	}
+
+	v.EditorFallthrough = true
+	if err := g.SimulateKeypress("", KeyArrowRight, 0, ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || v.cx != 1 {
+		t.Errorf("Expected the editor to handle the key got: %d calls, cursor x %d", calls, v.cx)
+	}
+	if err := g.SimulateKeypress("", KeyCtrlS, 0, ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected an unhandled key to reach the keybinding got: %d calls", calls)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	g, err := NewGui(OutputSimulator, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+
+	// The layouts are counted once the batch has started
+	var counting, layouts int32
+	g.SetManagerFunc(func(g *Gui) error {
+		if _, err := g.SetView("main", 0, 0, 20, 5, 0); err != nil && !errors.Is(err, ErrUnknownView) {
+			return err
+		}
+		if atomic.LoadInt32(&counting) == 1 {
+			atomic.AddInt32(&layouts, 1)
+		}
+		return nil
+	})
+	testingScreen := g.GetTestingScreen()
+	cleanup := testingScreen.StartGui()
+	defer cleanup()
+	// Let the events sent at startup, like the resize posted by
+	// SetManagerFunc, be handled
+	time.Sleep(20 * time.Millisecond)
+	testingScreen.WaitSync()
+
+	during := int32(-1)
+	errBatch := errors.New("batch error")
+	err = g.Batch(func() error {
+		done := make(chan struct{})
+		for i := 0; i < 1000; i++ {
+			i := i
+			g.UpdateAsync(func(g *Gui) error {
+				v, err := g.View("main")
+				if err != nil {
+					return err
+				}
+				switch i {
+				case 0:
+					atomic.StoreInt32(&counting, 1)
+				case 999:
+					during = atomic.LoadInt32(&layouts)
+					close(done)
+				}
+				fmt.Fprintln(v, "line")
+				return nil
+			})
+		}
+		// All the updates are handled before the batch ends
+		<-done
+		return errBatch
+	})
+	if err != errBatch {
+		t.Errorf("Expected the error of the batch got: %v", err)
+	}
+	if during != 0 {
+		t.Errorf("Expected no redraw during the batch got: %d", during)
+	}
+
+	// Nothing else updates the GUI, so a single redraw happens
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&layouts) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := atomic.LoadInt32(&layouts); n != 1 {
+		t.Errorf("Expected a single redraw after the batch got: %d", n)
+	}
+	v, err := g.View("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := v.LineCount(); n != 1001 {
+		t.Errorf("Expected 1001 lines got: %d", n)
+	}
+}
+
+func TestBatchWithoutMainLoop(t *testing.T) {
+	g, v := newTestGui(t)
+	if err := g.Batch(func() error {
+		fmt.Fprintln(v, "line")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing would handle the redraw
+	time.Sleep(10 * time.Millisecond)
+	if n := len(g.userEvents); n != 0 {
+		t.Errorf("Expected no pending update got: %d", n)
+	}
+}
+
+func TestTick(t *testing.T) {
+	g, _ := newTestGui(t)
+	testingScreen := g.GetTestingScreen()
+	cleanup := testingScreen.StartGui()
+	defer cleanup()
+
+	ticks := make(chan time.Time, 100)
+	g.OnTick = func(*Gui) error {
+		ticks <- time.Now()
+		return nil
+	}
+	const interval = 20 * time.Millisecond
+	start := time.Now()
+	g.SetTickInterval(interval)
+	for i := 0; i < 5; i++ {
+		select {
+		case <-ticks:
+		case <-time.After(time.Second):
+			t.Fatalf("Expected 5 ticks got: %d", i)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 5*interval-interval/2 {
+		t.Errorf("Expected 5 ticks to take about %v got: %v", 5*interval, elapsed)
+	}
+
+	g.SetTickInterval(0)
+	// A tick may have been sent just before the ticks were stopped
+	time.Sleep(2 * interval)
+	for len(ticks) > 0 {
+		<-ticks
+	}
+	time.Sleep(3 * interval)
+	if n := len(ticks); n != 0 {
+		t.Errorf("Expected no tick once stopped got: %d", n)
+	}
+}
+
+func TestOnFocusChange(t *testing.T) {
+	g, err := NewGui(OutputSimulator, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+	var got []string
+	g.OnFocusChange = func(g *Gui, prev, next *View) {
+		name := func(v *View) string {
+			if v == nil {
+				return "nil"
+			}
+			return v.Name()
+		}
+		if g.CurrentView() != next {
+			t.Errorf("Expected the current view to be %s in the callback", name(next))
+		}
+		got = append(got, name(prev)+"->"+name(next))
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := g.SetView(name, 0, 0, 10, 5, 0); !errors.Is(err, ErrUnknownView) {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"a", "b", "b", "a"} {
+		if _, err := g.SetCurrentView(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := g.SetCurrentView("c"); !errors.Is(err, ErrUnknownView) {
+		t.Errorf("Expected ErrUnknownView got: %v", err)
+	}
+
+	want := "nil->a a->b b->a"
+	if s := strings.Join(got, " "); s != want {
+		t.Errorf("Expected focus changes %q got: %q", want, s)
+	}
+}
+
+func TestFocusedFrameColor(t *testing.T) {
+	g, err := NewGui(OutputSimulator, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+	// Colors are dropped in the simulator mode
+	g.outputMode = OutputNormal
+	views := map[string]*View{}
+	for i, name := range []string{"a", "b"} {
+		v, err := g.SetView(name, i*10, 0, i*10+8, 4, 0)
+		if !errors.Is(err, ErrUnknownView) {
+			t.Fatal(err)
+		}
+		v.Frame = true
+		v.FrameColor, v.FrameBgColor = ColorBlue, ColorBlack
+		v.FocusedFrameFgColor = ColorRed
+		views[name] = v
+	}
+
+	for _, current := range []string{"a", "b"} {
+		if _, err := g.SetCurrentView(current); err != nil {
+			t.Fatal(err)
+		}
+		if err := g.flush(); err != nil {
+			t.Fatal(err)
+		}
+		for name, v := range views {
+			want := getTcellStyle(ColorBlue, ColorBlack, OutputNormal)
+			if name == current {
+				want = getTcellStyle(ColorRed, ColorBlack, OutputNormal)
+			}
+			for _, p := range [][2]int{{v.x0, v.y0}, {v.x0 + 3, v.y0}, {v.x0, v.y0 + 2}, {v.x1, v.y1}} {
+				if _, _, st, _ := screen.GetContent(p[0], p[1]); st != want {
+					t.Errorf("Current view %s: expected frame of %s to be drawn with %v at (%d, %d) got: %v", current, name, want, p[0], p[1], st)
+				}
+			}
+		}
+	}
+}
+
+// frameRow returns the runes drawn on the row y of the screen, from the
+// column x0 to the column x1.
+func frameRow(x0, x1, y int) string {
+	var b strings.Builder
+	for x := x0; x <= x1; x++ {
+		ch, _, _, _ := screen.GetContent(x, y)
+		b.WriteRune(ch)
+	}
+	return b.String()
+}
+
+func TestTitleLayout(t *testing.T) {
+	tests := []struct {
+		name            string
+		width           int
+		title, subtitle string
+		align           Alignment
+		want            string
+	}{
+		{"left", 16, "abc", "", AlignLeft, "┌─abc──────────┐"},
+		{"center", 16, "abc", "", AlignCenter, "┌─────abc──────┐"},
+		{"right", 16, "abc", "", AlignRight, "┌──────────abc─┐"},
+		{"subtitle", 16, "abc", "xy", AlignLeft, "┌─abc───xy─────┐"},
+		{"center subtitle", 16, "abc", "xy", AlignCenter, "┌───abc─xy─────┐"},
+		{"right subtitle", 16, "abc", "xy", AlignRight, "┌─xy───────abc─┐"},
+		{"truncated subtitle", 14, "abc", "wxyz", AlignLeft, "┌─abc─wx─────┐"},
+		{"center truncated subtitle", 14, "abc", "wxyz", AlignCenter, "┌─abc─wx─────┐"},
+		{"right truncated subtitle", 11, "abc", "wxyz", AlignRight, "┌─wxy─abc─┐"},
+		{"no room for the subtitle", 11, "abc", "xy", AlignLeft, "┌─abc─────┐"},
+		{"truncated title", 6, "abcdef", "xy", AlignLeft, "┌─ab─┐"},
+		{"truncated right title", 6, "abcdef", "xy", AlignRight, "┌─ab─┐"},
+		{"too narrow", 3, "abc", "xy", AlignCenter, "┌─┐"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGui(OutputSimulator, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(g.Close)
+			v, err := g.SetView("main", 0, 0, tt.width-1, 2, 0)
+			if !errors.Is(err, ErrUnknownView) {
+				t.Fatal(err)
+			}
+			v.Frame = true
+			v.Title, v.Subtitle, v.TitleAlignment = tt.title, tt.subtitle, tt.align
+			if err := g.flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := frameRow(0, tt.width-1, 0); got != tt.want {
+				t.Errorf("Expected top border %q got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFooter(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		footer string
+		align  Alignment
+		frame  bool
+		want   string
+	}{
+		{"left", 12, "1/10", AlignLeft, true, "└─1/10─────┘"},
+		{"center", 12, "1/10", AlignCenter, true, "└───1/10───┘"},
+		{"right", 12, "1/10", AlignRight, true, "└─────1/10─┘"},
+		{"truncated", 6, "1/10", AlignRight, true, "└─1/─┘"},
+		{"no frame", 12, "1/10", AlignLeft, false, "            "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := NewGui(OutputSimulator, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(g.Close)
+			v, err := g.SetView("main", 0, 0, tt.width-1, 2, 0)
+			if !errors.Is(err, ErrUnknownView) {
+				t.Fatal(err)
+			}
+			v.Frame = tt.frame
+			v.Title = "title"
+			v.Footer, v.FooterAlignment = tt.footer, tt.align
+			if err := g.flush(); err != nil {
+				t.Fatal(err)
+			}
+			if got := frameRow(0, tt.width-1, 2); got != tt.want {
+				t.Errorf("Expected bottom border %q got: %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFrameStyles(t *testing.T) {
+	tests := []struct {
+		name  string
+		runes []rune
+		want  []string
+	}{
+		{"default", nil, []string{"┌──┐", "│  │", "└──┘"}},
+		{"single", FrameStyleSingle, []string{"┌──┐", "│  │", "└──┘"}},
+		{"double", FrameStyleDouble, []string{"╔══╗", "║  ║", "╚══╝"}},
+		{"rounded", FrameStyleRounded, []string{"╭──╮", "│  │", "╰──╯"}},
+		{"ascii", FrameStyleASCII, []string{"+--+", "|  |", "+--+"}},
+	}
+
+	for _, tt := range tests {
+		for _, overlaps := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s overlaps %v", tt.name, overlaps), func(t *testing.T) {
+				g, err := NewGui(OutputSimulator, overlaps)
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(g.Close)
+				v, err := g.SetView("main", 0, 0, 3, 2, 0)
+				if !errors.Is(err, ErrUnknownView) {
+					t.Fatal(err)
+				}
+				v.Frame = true
+				v.FrameRunes = tt.runes
+				if err := g.flush(); err != nil {
+					t.Fatal(err)
+				}
+				for y, want := range tt.want {
+					if got := frameRow(0, 3, y); got != want {
+						t.Errorf("Expected row %d to be %q got: %q", y, want, got)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestFrameSides(t *testing.T) {
+	g, err := NewGui(OutputSimulator, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+	v, err := g.SetView("main", 0, 0, 5, 3, 0)
+	if !errors.Is(err, ErrUnknownView) {
+		t.Fatal(err)
+	}
+	v.Frame = true
+	if w, h := v.Size(); w != 4 || h != 2 {
+		t.Errorf("Expected size (4, 2) with all the sides got: (%d, %d)", w, h)
+	}
+
+	v.FrameSides = FrameSides{Top: true, Bottom: true}
+	if w, h := v.Size(); w != 6 || h != 2 {
+		t.Errorf("Expected size (6, 2) with the top and bottom sides got: (%d, %d)", w, h)
+	}
+	v.SetContent([]string{"abcdef", "ghijkl"})
+	if err := g.flush(); err != nil {
+		t.Fatal(err)
+	}
+	for y, want := range []string{"──────", "abcdef", "ghijkl", "──────"} {
+		if got := frameRow(0, 5, y); got != want {
+			t.Errorf("Expected row %d to be %q got: %q", y, want, got)
+		}
+	}
+	if x, y, err := v.screenToBuffer(0, 2); err != nil || x != 0 || y != 1 {
+		t.Errorf("Expected the point (0, 2) to be on the cell (0, 1) got: (%d, %d, %v)", x, y, err)
+	}
+	if found, err := g.ViewByPosition(5, 1); err != nil || found != v {
+		t.Errorf("Expected the point (5, 1) to be in the view got: %v", err)
+	}
+
+	v.FrameSides = FrameSides{Top: true, Left: true}
+	if w, h := v.Size(); w != 5 || h != 3 {
+		t.Errorf("Expected size (5, 3) with the top and left sides got: (%d, %d)", w, h)
+	}
+	if err := g.flush(); err != nil {
+		t.Fatal(err)
+	}
+	for y, want := range []string{"┌─────", "│abcde", "│ghijk", "│     "} {
+		if got := frameRow(0, 5, y); got != want {
+			t.Errorf("Expected row %d to be %q got: %q", y, want, got)
+		}
+	}
+}
+
+func TestScrollbar(t *testing.T) {
+	g, err := NewGui(OutputSimulator, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+	v, err := g.SetView("main", 0, 0, 10, 11, 0)
+	if !errors.Is(err, ErrUnknownView) {
+		t.Fatal(err)
+	}
+	v.Frame = true
+	v.ShowScrollbar = true
+
+	lines := make([]string, 40)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line%d", i)
+	}
+	v.SetContent(lines[:10])
+	if _, _, ok := v.scrollbarThumb(len(v.viewLines())); ok {
+		t.Error("Expected no scrollbar when the content fits")
+	}
+
+	v.SetContent(lines)
+	for _, tt := range []struct{ oy, start int }{{0, 0}, {1, 1}, {15, 4}, {29, 7}, {30, 8}} {
+		v.oy = tt.oy
+		start, size, ok := v.scrollbarThumb(len(v.viewLines()))
+		if !ok || start != tt.start || size != 2 {
+			t.Errorf("Origin %d: expected the thumb at %d of size 2 got: %d of size %d (%v)", tt.oy, tt.start, start, size, ok)
+		}
+	}
+
+	v.oy = 15
+	if err := g.flush(); err != nil {
+		t.Fatal(err)
+	}
+	var got strings.Builder
+	for y := 1; y <= 10; y++ {
+		ch, _, _, _ := screen.GetContent(10, y)
+		got.WriteRune(ch)
+	}
+	if want := "││││██││││"; got.String() != want {
+		t.Errorf("Expected the right edge to be %q got: %q", want, got.String())
+	}
+}
+
+func TestScrollbarConcurrentWrites(t *testing.T) {
+	g, err := NewGui(OutputSimulator, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+	v, err := g.SetView("main", 0, 0, 10, 11, 0)
+	if !errors.Is(err, ErrUnknownView) {
+		t.Fatal(err)
+	}
+	v.ShowScrollbar = true
+	v.Autoscroll = true
+
+	// The GUI is redrawn while the view is written, go test -race reports
+	// the accesses to the buffer that aren't locked
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(v, "line%d\n", i)
+			runtime.Gosched()
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+		}
+		if err := g.flush(); err != nil {
+			t.Fatal(err)
+		}
+		runtime.Gosched()
+	}
+
+	// The thumb shows the end of the content
+	if err := g.flush(); err != nil {
+		t.Fatal(err)
+	}
+	if v.thumbStart < 8 {
+		t.Errorf("Expected the thumb at the bottom got it at row %d", v.thumbStart)
+	}
+	if ch, _, _, _ := screen.GetContent(10, 1+v.thumbStart); ch != '█' {
+		t.Errorf("Expected the thumb to be drawn got: %q", ch)
+	}
+}
+
+// pasteEvents returns the events sent by the terminal for a bracketed paste
+// of text.
+func pasteEvents(text string) []gocuiEvent {
+	evs := []gocuiEvent{{Type: eventPasteStart}}
+	for _, r := range text {
+		switch r {
+		case '\n':
+			evs = append(evs, gocuiEvent{Type: eventKey, Key: KeyEnter})
+		case '\t':
+			evs = append(evs, gocuiEvent{Type: eventKey, Key: KeyTab})
+		case ' ':
+			evs = append(evs, gocuiEvent{Type: eventKey, Key: KeySpace})
+		default:
+			evs = append(evs, gocuiEvent{Type: eventKey, Ch: r})
+		}
+	}
+	return append(evs, gocuiEvent{Type: eventPasteEnd})
+}
+
+func TestBracketedPaste(t *testing.T) {
+	g, v := newTestGui(t)
+	v.AutoIndent = true
+	v.AutoPair = true
+
+	for _, tev := range []tcell.Event{tcell.NewEventPaste(true), tcell.NewEventPaste(false)} {
+		if err := screen.PostEvent(tev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ev := pollEvent(); ev.Type != eventPasteStart {
+		t.Errorf("Expected the start of a paste got event type: %v", ev.Type)
+	}
+	if ev := pollEvent(); ev.Type != eventPasteEnd {
+		t.Errorf("Expected the end of a paste got event type: %v", ev.Type)
+	}
+
+	text := "if x {\n\tf(a)\n}"
+	for _, ev := range pasteEvents(text) {
+		ev := ev
+		if err := g.handleEvent(&ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertBuffer(t, v, 1, 2, "if x {", "\tf(a)", "}")
+
+	// The whole paste is undone at once
+	v.Undo()
+	assertBuffer(t, v, 0, 0)
+
+	var pasted []string
+	v.OnPaste = func(v *View, text string) {
+		pasted = append(pasted, text)
+	}
+	for _, ev := range pasteEvents(text) {
+		ev := ev
+		if err := g.handleEvent(&ev); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(pasted) != 1 || pasted[0] != text {
+		t.Errorf("Expected OnPaste to be called once with %q got: %q", text, pasted)
+	}
+	assertBuffer(t, v, 0, 0)
+
+	// Keys are typed again after the paste
+	typeKeys(t, g, "(")
+	assertBuffer(t, v, 1, 0, "()")
+}
+
+func TestOnResize(t *testing.T) {
+	g, _ := newTestGui(t)
+	var calls []string
+	g.OnResize = func(g *Gui, width, height int) {
+		calls = append(calls, fmt.Sprintf("resize %dx%d", width, height))
+	}
+	g.SetManagerFunc(func(g *Gui) error {
+		calls = append(calls, "layout")
+		return nil
+	})
+	defer simulationScreen.SetSize(80, 25)
+
+	flush := func() {
+		t.Helper()
+		if err := g.flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flush()
+	flush()
+	simulationScreen.SetSize(100, 30)
+	flush()
+
+	want := "resize 80x25,layout,layout,resize 100x30,layout"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("Expected calls %q got: %q", want, got)
+	}
+}
@@ -23,7 +23,22 @@ type keybinding struct {
 	key      Key
 	ch       rune
 	mod      Modifier
-	handler  func(*Gui, *View) error
+	handler  func(*Gui, *View, Key, rune, Modifier) error
+	disabled bool
+
+	// keys is the whole sequence of a keybinding sequence, nil otherwise
+	keys []keyPress
+
+	// match reports whether a key triggers a keybinding created with
+	// SetKeybindingFunc, nil otherwise
+	match func(Key, rune, Modifier) bool
+}
+
+// keyPress is a key of a keybinding sequence.
+type keyPress struct {
+	key Key
+	ch  rune
+	mod Modifier
 }
 
 // Parse takes the input string and extracts the keybinding.
@@ -92,7 +107,7 @@ func MustParseAll(input []string) map[interface{}]Modifier {
 }
 
 // newKeybinding returns a new Keybinding object.
-func newKeybinding(viewname string, key Key, ch rune, mod Modifier, handler func(*Gui, *View) error) (kb *keybinding) {
+func newKeybinding(viewname string, key Key, ch rune, mod Modifier, handler func(*Gui, *View, Key, rune, Modifier) error) (kb *keybinding) {
 	kb = &keybinding{
 		viewName: viewname,
 		key:      key,
@@ -103,11 +118,34 @@ func newKeybinding(viewname string, key Key, ch rune, mod Modifier, handler func
 	return kb
 }
 
+// simpleHandler adapts a keybinding handler that doesn't need the key press.
+func simpleHandler(handler func(*Gui, *View) error) func(*Gui, *View, Key, rune, Modifier) error {
+	if handler == nil {
+		return nil
+	}
+	return func(g *Gui, v *View, _ Key, _ rune, _ Modifier) error {
+		return handler(g, v)
+	}
+}
+
 // matchKeypress returns if the keybinding matches the keypress.
 func (kb *keybinding) matchKeypress(key Key, ch rune, mod Modifier) bool {
 	return kb.key == key && kb.ch == ch && kb.mod == mod
 }
 
+// matchSequence returns if the keys start the keybinding sequence.
+func (kb *keybinding) matchSequence(keys []keyPress) bool {
+	if len(keys) > len(kb.keys) {
+		return false
+	}
+	for i, k := range keys {
+		if kb.keys[i] != k {
+			return false
+		}
+	}
+	return true
+}
+
 // matchView returns if the keybinding matches the current view.
 func (kb *keybinding) matchView(v *View) bool {
 	// if the user is typing in a field, ignore char keys
@@ -0,0 +1,44 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "strings"
+
+// killRingSize is the maximum number of kills kept by a view.
+const killRingSize = 32
+
+// pushKill adds the given text to the kill ring, dropping the oldest kill if
+// the ring is full.
+func (v *View) pushKill(text string) {
+	if text == "" {
+		return
+	}
+	v.killRing = append(v.killRing, text)
+	if over := len(v.killRing) - killRingSize; over > 0 {
+		v.killRing = v.killRing[over:]
+	}
+}
+
+// LastKill returns the text most recently removed by EditDeleteToStartOfLine,
+// EditDeleteToEndOfLine or EditDeleteWord. It returns an empty string if
+// nothing was killed yet.
+func (v *View) LastKill() string {
+	if len(v.killRing) == 0 {
+		return ""
+	}
+	return v.killRing[len(v.killRing)-1]
+}
+
+// EditYank inserts the last killed text at the cursor position, like ctrl+Y
+// in your terminal. It does nothing if nothing was killed yet.
+func (v *View) EditYank() {
+	v.EditWriteString(v.LastKill())
+}
+
+// cellsText returns the text of the given cells, NUL cells are translated to
+// spaces.
+func cellsText(cells []cell) string {
+	return strings.Replace(lineType(cells).String(), "\x00", " ", -1)
+}
@@ -0,0 +1,55 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "testing"
+
+func TestKillAndYank(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        []string
+		cx, cy       int
+		kill         func(v *View)
+		want         string
+		wantX, wantY int
+	}{
+		{"to start of line", []string{"hello", "world"}, 5, 1, (*View).EditDeleteToStartOfLine, "world", 5, 1},
+		{"to end of line", []string{"hello", "world"}, 2, 0, (*View).EditDeleteToEndOfLine, "llo", 5, 0},
+		{"word", []string{"hello", "big world"}, 9, 1, (*View).EditDeleteWord, "world", 9, 1},
+		{"line break backwards", []string{"hello", "world"}, 0, 1, (*View).EditDeleteToStartOfLine, "\n", 0, 1},
+		{"line break forwards", []string{"hello", "world"}, 5, 0, (*View).EditDeleteToEndOfLine, "\n", 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.lines...)
+			v.SetCursor(tt.cx, tt.cy)
+			tt.kill(v)
+			if got := v.LastKill(); got != tt.want {
+				t.Errorf("Expected last kill to be: %q got: %q", tt.want, got)
+			}
+
+			v.EditYank()
+			assertBuffer(t, v, tt.wantX, tt.wantY, tt.lines...)
+		})
+	}
+}
+
+func TestKillRingEmpty(t *testing.T) {
+	v := newTestView(20, 5, "hello")
+	v.SetCursor(2, 0)
+	if got := v.LastKill(); got != "" {
+		t.Errorf("Expected no kill got: %q", got)
+	}
+	v.EditYank()
+	assertBuffer(t, v, 2, 0, "hello")
+
+	for i := 0; i < killRingSize+5; i++ {
+		v.pushKill("x")
+	}
+	if len(v.killRing) != killRingSize {
+		t.Errorf("Expected kill ring to hold %d kills got: %d", killRingSize, len(v.killRing))
+	}
+}
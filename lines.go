@@ -0,0 +1,150 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+// minLineGap is the minimum number of free slots allocated when a lineBuffer
+// grows.
+const minLineGap = 16
+
+// lineBuffer holds the lines of a view's internal buffer in a gap buffer:
+// buf stores the lines around a gap of free slots, which is moved to the
+// place where lines are inserted or removed. Editing around the same line
+// only moves the lines between two edits instead of the whole tail of the
+// buffer. Its zero value is an empty buffer.
+type lineBuffer struct {
+	buf [][]cell
+
+	// The gap is buf[gap0:gap1], its slots are nil so that the removed
+	// lines can be freed
+	gap0, gap1 int
+}
+
+// newLineBuffer returns a buffer holding lines, it takes the ownership of
+// the slice.
+func newLineBuffer(lines [][]cell) lineBuffer {
+	return lineBuffer{buf: lines, gap0: len(lines), gap1: len(lines)}
+}
+
+// len returns the number of lines of the buffer.
+func (b *lineBuffer) len() int {
+	return len(b.buf) - (b.gap1 - b.gap0)
+}
+
+// index returns the index of the line y in buf.
+func (b *lineBuffer) index(y int) int {
+	if y >= b.gap0 {
+		return y + b.gap1 - b.gap0
+	}
+	return y
+}
+
+// line returns the line y.
+func (b *lineBuffer) line(y int) []cell {
+	return b.buf[b.index(y)]
+}
+
+// set replaces the line y.
+func (b *lineBuffer) set(y int, line []cell) {
+	b.buf[b.index(y)] = line
+}
+
+// slice returns a copy of the lines from y0 to y1, y1 excluded.
+func (b *lineBuffer) slice(y0, y1 int) [][]cell {
+	if y0 < 0 || y1 < y0 || y1 > b.len() {
+		panic("invalid lines")
+	}
+	lines := make([][]cell, 0, y1-y0)
+	if y0 < b.gap0 {
+		end := y1
+		if end > b.gap0 {
+			end = b.gap0
+		}
+		lines = append(lines, b.buf[y0:end]...)
+		y0 = end
+	}
+	return append(lines, b.buf[b.index(y0):b.index(y1)]...)
+}
+
+// insert inserts lines before the line y, y can be the number of lines of
+// the buffer to append them.
+func (b *lineBuffer) insert(y int, lines ...[]cell) {
+	if y < 0 || y > b.len() {
+		panic("invalid line")
+	}
+	if b.gap1-b.gap0 < len(lines) {
+		b.grow(len(lines))
+	}
+	b.moveGap(y)
+	b.gap0 += copy(b.buf[b.gap0:], lines)
+}
+
+// append adds lines at the end of the buffer.
+func (b *lineBuffer) append(lines ...[]cell) {
+	b.insert(b.len(), lines...)
+}
+
+// remove removes n lines from the line y.
+func (b *lineBuffer) remove(y, n int) {
+	if y < 0 || n < 0 || y+n > b.len() {
+		panic("invalid lines")
+	}
+	if y == 0 && n <= b.gap0 {
+		// The oldest lines are dropped without moving the gap, e.g. for
+		// MaxLines
+		clearLines(b.buf[:n])
+		b.buf = b.buf[n:]
+		b.gap0 -= n
+		b.gap1 -= n
+		return
+	}
+	b.moveGap(y)
+	clearLines(b.buf[b.gap1 : b.gap1+n])
+	b.gap1 += n
+}
+
+// moveGap moves the gap before the line y.
+func (b *lineBuffer) moveGap(y int) {
+	n := b.gap1 - b.gap0
+	if y < b.gap0 {
+		// The lines from y are moved after the gap
+		copy(b.buf[y+n:], b.buf[y:b.gap0])
+		end := b.gap0
+		if end > y+n {
+			end = y + n
+		}
+		clearLines(b.buf[y:end])
+	} else if y > b.gap0 {
+		// The lines up to y are moved before the gap
+		copy(b.buf[b.gap0:], b.buf[b.gap1:y+n])
+		start := b.gap1
+		if start < y {
+			start = y
+		}
+		clearLines(b.buf[start : y+n])
+	}
+	b.gap0, b.gap1 = y, y+n
+}
+
+// grow reallocates buf with a gap of at least n slots.
+func (b *lineBuffer) grow(n int) {
+	gap := b.len()
+	if gap < n {
+		gap = n
+	}
+	if gap < minLineGap {
+		gap = minLineGap
+	}
+	buf := make([][]cell, b.len()+gap)
+	copy(buf, b.buf[:b.gap0])
+	copy(buf[b.gap0+gap:], b.buf[b.gap1:])
+	b.buf, b.gap1 = buf, b.gap0+gap
+}
+
+// clearLines sets lines to nil so that they can be freed.
+func clearLines(lines [][]cell) {
+	for i := range lines {
+		lines[i] = nil
+	}
+}
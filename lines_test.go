@@ -0,0 +1,68 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestLineBuffer(t *testing.T) {
+	var b lineBuffer
+	var want [][]cell
+	line := func(i int) []cell {
+		return []cell{{chr: rune('a' + i%26)}}
+	}
+	check := func(op string) {
+		t.Helper()
+		if n := b.len(); n != len(want) {
+			t.Fatalf("%s: expected %d lines got: %d", op, len(want), n)
+		}
+		if len(want) > 0 && !reflect.DeepEqual(b.slice(0, b.len()), want) {
+			t.Fatalf("%s: expected %v got: %v", op, want, b.slice(0, b.len()))
+		}
+		for i, l := range b.buf[b.gap0:b.gap1] {
+			if l != nil {
+				t.Fatalf("%s: expected the slot %d of the gap to be freed", op, b.gap0+i)
+			}
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		switch y := r.Intn(len(want) + 1); {
+		case r.Intn(3) > 0 || len(want) == 0:
+			b.insert(y, line(i), line(i+1))
+			want = append(want[:y], append([][]cell{line(i), line(i + 1)}, want[y:]...)...)
+			check("insert")
+		case y == len(want):
+			b.remove(0, 1)
+			want = want[1:]
+			check("remove the first line")
+		default:
+			l := line(i + 2)
+			b.set(y, l)
+			if got := b.line(y); !reflect.DeepEqual(got, l) {
+				t.Fatalf("set: expected %v got: %v", l, got)
+			}
+			b.remove(y, 1)
+			want = append(want[:y], want[y+1:]...)
+			check("remove")
+		}
+	}
+}
+
+func TestLineBufferFreesRemovedLines(t *testing.T) {
+	b := newLineBuffer([][]cell{{{chr: 'a'}}, {{chr: 'b'}}, {{chr: 'c'}}})
+	b.remove(1, 2)
+	b.insert(0, []cell{{chr: 'x'}})
+	b.remove(0, 2)
+	for i, l := range b.buf {
+		if l != nil {
+			t.Errorf("Expected the slot %d to be freed got: %v", i, l)
+		}
+	}
+}
@@ -1,15 +1,31 @@
 package gocui
 
-import "time"
+import (
+	"sync/atomic"
+	"time"
+)
 
+// loaderTick redraws the GUI while a view has a loader, until the GUI is
+// closed.
 func (g *Gui) loaderTick() {
+	g.loops.Add(1)
 	go func() {
-		for range time.Tick(time.Millisecond * 50) {
-			for _, view := range g.Views() {
-				if view.HasLoader {
-					g.userEvents <- userEvent{func(g *Gui) error { return nil }}
-					break
-				}
+		defer g.loops.Done()
+		ticker := time.NewTicker(time.Millisecond * 50)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-g.closed:
+				return
+			}
+			if atomic.LoadInt32(&g.hasLoader) == 0 {
+				continue
+			}
+			select {
+			case g.userEvents <- userEvent{func(g *Gui) error { return nil }}:
+			case <-g.closed:
+				return
 			}
 		}
 	}()
@@ -0,0 +1,69 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+// searchMatch is a range of a line of the view's internal buffer that
+// matches the search pattern. It starts at the cell x0 and ends before the
+// cell x1.
+type searchMatch struct {
+	x0, x1 int
+}
+
+// SetSearchHighlight highlights all the occurrences of pattern in the view's
+// internal buffer using Search{Bg,Fg}Colors. The matches are updated every
+// time the view is redrawn after its buffer changed. An empty pattern
+// removes the highlight.
+func (v *View) SetSearchHighlight(pattern string) {
+	v.tainted = true
+	v.searchPattern = []rune(pattern)
+}
+
+// ClearSearchHighlight removes the highlight set with SetSearchHighlight.
+func (v *View) ClearSearchHighlight() {
+	v.SetSearchHighlight("")
+}
+
+// updateSearchMatches finds the occurrences of the search pattern in the
+// view's internal buffer. Overlapping occurrences are all matched.
+func (v *View) updateSearchMatches() {
+	v.searchMatches = nil
+	if len(v.searchPattern) == 0 {
+		return
+	}
+
+	v.searchMatches = make([][]searchMatch, v.lines.len())
+	for y := range v.searchMatches {
+		line := v.lines.line(y)
+		for x := 0; x+len(v.searchPattern) <= len(line); x++ {
+			if matchRunes(line[x:], v.searchPattern) {
+				v.searchMatches[y] = append(v.searchMatches[y], searchMatch{x0: x, x1: x + len(v.searchPattern)})
+			}
+		}
+	}
+}
+
+// matchRunes reports whether the given cells start with the given runes.
+func matchRunes(cells []cell, runes []rune) bool {
+	for i, r := range runes {
+		if cells[i].chr != r {
+			return false
+		}
+	}
+	return true
+}
+
+// isSearchMatch reports whether the cell (x, y) of the view's internal
+// buffer is part of a search match.
+func (v *View) isSearchMatch(x, y int) bool {
+	if y >= len(v.searchMatches) {
+		return false
+	}
+	for _, m := range v.searchMatches[y] {
+		if x >= m.x0 && x < m.x1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "testing"
+
+func TestSearchHighlight(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		pattern string
+		want    []string // 'x' marks the highlighted cells
+	}{
+		{"single match", []string{"hello world"}, "wor", []string{"      xxx"}},
+		{"adjacent matches", []string{"abab-ab"}, "ab", []string{"xxxx xx"}},
+		{"overlapping matches", []string{"aaa b aa"}, "aa", []string{"xxx   xx"}},
+		{"multiple lines", []string{"foo", "bar foo", "fo"}, "foo", []string{"xxx", "    xxx", "  "}},
+		{"no match", []string{"hello"}, "xyz", []string{"     "}},
+		{"empty pattern", []string{"hello"}, "", []string{"     "}},
+	}
+
+	hlStyle := getTcellStyle(ColorBlack, ColorYellow, OutputNormal)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, tt.lines...)
+			v.SearchFgColor, v.SearchBgColor = ColorBlack, ColorYellow
+			v.SetSearchHighlight(tt.pattern)
+			drawTestView(t, v)
+
+			for y, line := range tt.want {
+				for x, m := range line {
+					_, st := viewCell(v, x, y)
+					if hl := st == hlStyle; hl != (m == 'x') {
+						t.Errorf("Expected highlight of (%d, %d) to be %v got: %v", x, y, m == 'x', hl)
+					}
+				}
+			}
+		})
+	}
+
+	t.Run("buffer changes", func(t *testing.T) {
+		v := newTestView(20, 5, "foo bar")
+		v.SearchFgColor, v.SearchBgColor = ColorBlack, ColorYellow
+		v.SetSearchHighlight("bar")
+		drawTestView(t, v)
+
+		v.SetCursor(0, 0)
+		v.EditWriteString("bar ")
+		drawTestView(t, v)
+		for x := 0; x < 11; x++ {
+			_, st := viewCell(v, x, 0)
+			want := x < 3 || x >= 8
+			if hl := st == hlStyle; hl != want {
+				t.Errorf("Expected highlight of (%d, 0) to be %v got: %v", x, want, hl)
+			}
+		}
+
+		v.ClearSearchHighlight()
+		drawTestView(t, v)
+		if _, st := viewCell(v, 0, 0); st == hlStyle {
+			t.Error("Expected highlight to be cleared")
+		}
+	})
+}
@@ -0,0 +1,260 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "strings"
+
+// selection is a range of the view's internal buffer. It starts at the cell
+// (x0, y0) and ends before the cell (x1, y1). If block is true, it's the
+// rectangle of the cells [x0, x1) of the lines y0 to y1 instead.
+type selection struct {
+	x0, y0, x1, y1 int
+	block          bool
+}
+
+// SetSelection selects the text of the view's internal buffer between the
+// given points, the cell at the end point is not part of the selection. The
+// points can be given in any order and are clipped to the buffer bounds.
+// Selected cells are drawn using Sel{Bg,Fg}Colors.
+func (v *View) SetSelection(startX, startY, endX, endY int) {
+	startX, startY = v.clipPoint(startX, startY)
+	endX, endY = v.clipPoint(endX, endY)
+	if endY < startY || (endY == startY && endX < startX) {
+		startX, startY, endX, endY = endX, endY, startX, startY
+	}
+
+	v.tainted = true
+	v.selection = &selection{x0: startX, y0: startY, x1: endX, y1: endY}
+}
+
+// SetBlockSelection selects the rectangle of cells of the view's internal
+// buffer that has the given points as corners, like SetSelection the cells
+// of the column x2 are not part of it. The corners can be given in any order,
+// the lines are clipped to the buffer but the columns aren't: only the cells
+// of the shorter lines that are inside the rectangle are selected.
+func (v *View) SetBlockSelection(x1, y1, x2, y2 int) {
+	if x2 < x1 {
+		x1, x2 = x2, x1
+	}
+	if y2 < y1 {
+		y1, y2 = y2, y1
+	}
+	if x1 < 0 {
+		x1 = 0
+	}
+	if x2 < 0 {
+		x2 = 0
+	}
+	_, y1 = v.clipPoint(0, y1)
+	_, y2 = v.clipPoint(0, y2)
+
+	v.tainted = true
+	v.selection = &selection{x0: x1, y0: y1, x1: x2, y1: y2, block: true}
+}
+
+// DeleteBlockSelection deletes the cells selected with SetBlockSelection from
+// each line of the rectangle, removes the selection and moves the cursor to
+// its top left corner. It does nothing if the selection isn't a block.
+func (v *View) DeleteBlockSelection() {
+	s := v.selection
+	if s == nil || !s.block {
+		return
+	}
+
+	v.beginEdit()
+	defer v.endEdit()
+
+	for y := s.y0; y <= s.y1 && y < v.lines.len(); y++ {
+		end := s.x1
+		if n := len(v.lines.line(y)); end > n {
+			end = n
+		}
+		if s.x0 < end {
+			_ = v.deleteRunes(s.x0, end, y)
+		}
+	}
+	v.ClearSelection()
+	v.placeCursor(v.clipPoint(s.x0, s.y0))
+}
+
+// DeleteSelection deletes the selected text, removes the selection and
+// moves the cursor to its start. A block selection is deleted like with
+// DeleteBlockSelection. It does nothing if there is no selection.
+func (v *View) DeleteSelection() {
+	s := v.selection
+	if s == nil {
+		return
+	}
+	if s.block {
+		v.DeleteBlockSelection()
+		return
+	}
+
+	v.beginEdit()
+	defer v.endEdit()
+
+	v.ClearSelection()
+	x0, y0 := v.clipPoint(s.x0, s.y0)
+	x1, y1 := v.clipPoint(s.x1, s.y1)
+	if v.lines.len() > 0 {
+		_ = v.deleteText(x0, y0, x1, y1)
+	}
+	v.placeCursor(x0, y0)
+}
+
+// ClearSelection removes the selection of the view.
+func (v *View) ClearSelection() {
+	v.tainted = true
+	v.selection = nil
+}
+
+// SelectedText returns the selected text, lines are joined with '\n'. It
+// returns an empty string if there is no selection.
+func (v *View) SelectedText() string {
+	if v.selection == nil {
+		return ""
+	}
+	s := *v.selection
+
+	lines := []string{}
+	for y := s.y0; y <= s.y1 && y < v.lines.len(); y++ {
+		line := v.lines.line(y)
+		start, end := 0, len(line)
+		if s.block || y == s.y0 {
+			start = s.x0
+		}
+		if (s.block || y == s.y1) && s.x1 < end {
+			end = s.x1
+		}
+		if start > end {
+			start = end
+		}
+		str := lineType(line[start:end]).String()
+		lines = append(lines, strings.Replace(str, "\x00", " ", -1))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// selectedLines returns the first and the last line of the selection, or the
+// line under the cursor if there is no selection. A selection that ends at the
+// start of a line doesn't include that line. It returns an empty range if the
+// cursor isn't on a line of the view's internal buffer.
+func (v *View) selectedLines() (y0, y1 int) {
+	s := v.selection
+	if s == nil {
+		if v.cy >= v.lines.len() {
+			return 0, -1
+		}
+		return v.cy, v.cy
+	}
+
+	y0, y1 = s.y0, s.y1
+	if !s.block && y1 > y0 && s.x1 == 0 {
+		y1--
+	}
+	if y1 >= v.lines.len() {
+		y1 = v.lines.len() - 1
+	}
+	return y0, y1
+}
+
+// isSelected reports whether the cell (x, y) of the view's internal buffer
+// is selected.
+func (v *View) isSelected(x, y int) bool {
+	s := v.selection
+	if s == nil || y < s.y0 || y > s.y1 {
+		return false
+	}
+	if s.block {
+		return x >= s.x0 && x < s.x1
+	}
+	if y == s.y0 && x < s.x0 {
+		return false
+	}
+	if y == s.y1 && x >= s.x1 {
+		return false
+	}
+	return true
+}
+
+// startDrag moves the cursor to the point (sx, sy) of the screen and anchors
+// a mouse selection there. Any previous selection is removed.
+func (v *View) startDrag(sx, sy int) error {
+	if err := v.SetCursorFromScreen(sx, sy); err != nil {
+		return err
+	}
+	v.dragX, v.dragY = v.cx, v.cy
+	v.ClearSelection()
+	return nil
+}
+
+// dragTo extends the mouse selection started by startDrag to the point
+// (sx, sy) of the screen and moves the cursor there. Points outside of the
+// view are clipped to its content area, scrolling the view by one row or
+// column towards them if there is more content in that direction.
+func (v *View) dragTo(sx, sy int) {
+	maxX, maxY := v.Size()
+	if maxX <= 0 || maxY <= 0 {
+		return
+	}
+	gutter := v.gutterWidth()
+	cx, cy, _, _ := v.contentArea()
+	vx, vy := sx-cx-gutter, sy-cy
+
+	switch {
+	case vy < 0:
+		if v.oy > 0 {
+			v.oy--
+		}
+		vy = 0
+	case vy >= maxY:
+		if v.oy+maxY < len(v.viewLines()) {
+			v.oy++
+		}
+		vy = maxY - 1
+	}
+	switch {
+	case vx < 0:
+		if v.ox > 0 && !v.Wrap {
+			v.ox--
+		}
+		vx = 0
+	case vx >= maxX:
+		if !v.Wrap && v.ox+maxX < v.maxLineWidth() {
+			v.ox++
+		}
+		vx = maxX - 1
+	}
+
+	// The point is inside the content area now
+	_ = v.SetCursorFromScreen(cx+gutter+vx, cy+vy)
+	v.SetSelection(v.dragX, v.dragY, v.cx, v.cy)
+}
+
+// selectWord selects the word under the cursor, as returned by CursorWord.
+// If there is no word, the cell under the cursor is selected.
+func (v *View) selectWord() {
+	word, start := v.CursorWord()
+	if word == "" {
+		v.SetSelection(v.cx, v.cy, v.cx+1, v.cy)
+		return
+	}
+
+	line := v.lines.line(v.cy)
+	end := start
+	for end < len(line) && !v.isWordDelimiter(line[end].chr) {
+		end++
+	}
+	v.SetSelection(start, v.cy, end, v.cy)
+}
+
+// selectLine selects the whole logical line under the cursor.
+func (v *View) selectLine() {
+	end := 0
+	if v.cy < v.lines.len() {
+		end = len(v.lines.line(v.cy))
+	}
+	v.SetSelection(0, v.cy, end, v.cy)
+}
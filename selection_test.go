@@ -0,0 +1,235 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelectedText(t *testing.T) {
+	lines := []string{"hello world", "foo bar", "baz"}
+	tests := []struct {
+		name           string
+		x0, y0, x1, y1 int
+		want           string
+	}{
+		{"single line", 6, 0, 11, 0, "world"},
+		{"multi line", 6, 0, 3, 1, "world\nfoo"},
+		{"reversed", 3, 2, 4, 1, "bar\nbaz"},
+		{"clipped", -5, -1, 99, 9, "hello world\nfoo bar\nbaz"},
+		{"empty", 2, 1, 2, 1, ""},
+		{"from the end of a line", 11, 0, 3, 1, "\nfoo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.SetSelection(tt.x0, tt.y0, tt.x1, tt.y1)
+			if got := v.SelectedText(); got != tt.want {
+				t.Errorf("Expected selected text to be: %q got: %q", tt.want, got)
+			}
+		})
+	}
+
+	v := newTestView(20, 5, lines...)
+	v.SetSelection(0, 0, 5, 0)
+	v.ClearSelection()
+	if got := v.SelectedText(); got != "" {
+		t.Errorf("Expected no selected text after ClearSelection got: %q", got)
+	}
+}
+
+func TestSelectionRendering(t *testing.T) {
+	v := newTestView(10, 3, "abcd", "efgh")
+	v.SelFgColor, v.SelBgColor = ColorRed, ColorBlue
+	v.SetSelection(3, 1, 2, 0)
+	drawTestView(t, v)
+
+	selected := getTcellStyle(ColorRed, ColorBlue, OutputNormal)
+	for _, p := range []struct {
+		x, y int
+		sel  bool
+	}{{1, 0, false}, {2, 0, true}, {3, 0, true}, {0, 1, true}, {2, 1, true}, {3, 1, false}} {
+		if _, st := viewCell(v, p.x, p.y); (st == selected) != p.sel {
+			t.Errorf("Expected cell (%d, %d) selected to be %v", p.x, p.y, p.sel)
+		}
+	}
+}
+
+func TestBlockSelection(t *testing.T) {
+	lines := []string{"id name  age", "1", "22 bob", "333 alice 42"}
+	v := newTestView(20, 5, lines...)
+	v.SetBlockSelection(8, 3, 3, 0)
+	if got, want := v.SelectedText(), "name \n\nbob\n alic"; got != want {
+		t.Errorf("Expected selected text to be: %q got: %q", want, got)
+	}
+
+	v.SelFgColor, v.SelBgColor = ColorRed, ColorBlue
+	drawTestView(t, v)
+	selected := getTcellStyle(ColorRed, ColorBlue, OutputNormal)
+	for _, p := range []struct {
+		x, y int
+		sel  bool
+	}{{2, 0, false}, {3, 0, true}, {7, 0, true}, {8, 0, false}, {3, 2, true}, {2, 3, false}, {7, 3, true}, {8, 3, false}} {
+		if _, st := viewCell(v, p.x, p.y); (st == selected) != p.sel {
+			t.Errorf("Expected cell (%d, %d) selected to be %v", p.x, p.y, p.sel)
+		}
+	}
+
+	v.SetCursor(0, 0)
+	v.DeleteBlockSelection()
+	assertBuffer(t, v, 3, 0, "id  age", "1", "22 ", "333e 42")
+	if got := v.SelectedText(); got != "" {
+		t.Errorf("Expected no selected text after deletion got: %q", got)
+	}
+
+	v.Undo()
+	assertBuffer(t, v, 0, 0, lines...)
+
+	// Without block selection nothing is deleted
+	v.SetSelection(0, 0, 2, 0)
+	v.DeleteBlockSelection()
+	assertBuffer(t, v, 0, 0, lines...)
+}
+
+func TestReplaceSelection(t *testing.T) {
+	lines := []string{"hello world", "second line", "third"}
+	tests := []struct {
+		name           string
+		x0, y0, x1, y1 int
+		write          func(v *View)
+		wantX, wantY   int
+		want           []string
+	}{
+		{"rune", 6, 0, 11, 0, func(v *View) { v.EditWrite('X') }, 7, 0, []string{"hello X", "second line", "third"}},
+		{"string", 0, 0, 5, 0, func(v *View) { v.EditWriteString("bye") }, 3, 0, []string{"bye world", "second line", "third"}},
+		{"multiple lines", 6, 0, 7, 1, func(v *View) { v.EditWriteString("new") }, 9, 0, []string{"hello newline", "third"}},
+		{"multiple lines by lines", 3, 0, 2, 2, func(v *View) { v.EditWriteString("a\nb") }, 1, 1, []string{"hela", "bird"}},
+		{"new line", 5, 0, 6, 0, func(v *View) { v.EditNewLine() }, 0, 1, []string{"hello", "world", "second line", "third"}},
+		{"empty selection", 2, 1, 2, 1, func(v *View) { v.EditWrite('-') }, 3, 1, []string{"hello world", "se-cond line", "third"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.SetCursor(tt.x1, tt.y1)
+			v.SetSelection(tt.x0, tt.y0, tt.x1, tt.y1)
+			tt.write(v)
+			assertBuffer(t, v, tt.wantX, tt.wantY, tt.want...)
+			if v.selection != nil {
+				t.Error("Expected the selection to be removed")
+			}
+
+			// The replacement is undone at once
+			v.Undo()
+			assertBuffer(t, v, tt.x1, tt.y1, lines...)
+		})
+	}
+
+	t.Run("block", func(t *testing.T) {
+		v := newTestView(20, 5, "abcd", "efgh")
+		v.SetBlockSelection(1, 0, 3, 1)
+		v.EditWrite('x')
+		assertBuffer(t, v, 2, 0, "axd", "eh")
+	})
+}
+
+func TestMouseDrag(t *testing.T) {
+	v := newTestView(10, 3, "line0", "line1", "line2", "line3", "line4", "line5")
+
+	if err := v.startDrag(0, 1); err != ErrInvalidPoint {
+		t.Errorf("Expected ErrInvalidPoint starting a drag on the frame got: %v", err)
+	}
+	if err := v.startDrag(3, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.SelectedText(); got != "" {
+		t.Errorf("Expected no selected text after starting a drag got: %q", got)
+	}
+
+	steps := []struct {
+		name           string
+		sx, sy         int
+		wantCx, wantCy int
+		wantOy         int
+		want           string
+	}{
+		{"inside", 4, 2, 3, 1, 0, "ne0\nlin"},
+		{"below", 2, 9, 1, 3, 1, "ne0\nline1\nline2\nl"},
+		{"below again", 2, 9, 1, 4, 2, "ne0\nline1\nline2\nline3\nl"},
+		{"back inside", 6, 1, 5, 2, 2, "ne0\nline1\nline2"},
+		{"above", -3, -3, 0, 1, 1, "ne0\n"},
+		{"above again", 1, 0, 0, 0, 0, "li"},
+	}
+	for _, s := range steps {
+		v.dragTo(s.sx, s.sy)
+		if v.cx != s.wantCx || v.cy != s.wantCy || v.oy != s.wantOy {
+			t.Errorf("%s: Expected cursor (%d, %d) origin y %d got: (%d, %d) %d",
+				s.name, s.wantCx, s.wantCy, s.wantOy, v.cx, v.cy, v.oy)
+		}
+		if got := v.SelectedText(); got != s.want {
+			t.Errorf("%s: Expected selected text to be: %q got: %q", s.name, s.want, got)
+		}
+	}
+}
+
+func TestSelectWordAndLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		sx, sy   int
+		wantWord string
+		wantLine string
+	}{
+		{"word start", 1, 1, "hello", "hello, world"},
+		{"word middle", 10, 1, "world", "hello, world"},
+		{"after word", 6, 1, "hello", "hello, world"},
+		{"delimiter", 7, 1, " ", "hello, world"},
+		{"past line end", 9, 2, "bar", "foo.bar"},
+		{"second line", 2, 2, "foo", "foo.bar"},
+		{"past buffer end", 6, 4, "bar", "foo.bar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, "hello, world", "foo.bar")
+			if err := v.startDrag(tt.sx, tt.sy); err != nil {
+				t.Fatal(err)
+			}
+			v.selectWord()
+			if got := v.SelectedText(); got != tt.wantWord {
+				t.Errorf("Expected word to be: %q got: %q", tt.wantWord, got)
+			}
+			v.selectLine()
+			if got := v.SelectedText(); got != tt.wantLine {
+				t.Errorf("Expected line to be: %q got: %q", tt.wantLine, got)
+			}
+		})
+	}
+}
+
+func TestCountClick(t *testing.T) {
+	g := &Gui{DoubleClickInterval: defaultDoubleClickInterval}
+	start := time.Now()
+	clicks := []struct {
+		x, y  int
+		after time.Duration
+		want  int
+	}{
+		{1, 1, 0, 1},
+		{1, 1, 100 * time.Millisecond, 2},
+		{1, 1, 200 * time.Millisecond, 3},
+		{1, 1, 300 * time.Millisecond, 1},
+		{2, 1, 400 * time.Millisecond, 1},
+		{2, 1, 1000 * time.Millisecond, 1},
+		{2, 1, 1100 * time.Millisecond, 2},
+	}
+
+	for i, c := range clicks {
+		if got := g.countClick(c.x, c.y, start.Add(c.after)); got != c.want {
+			t.Errorf("click %d: Expected %d consecutive clicks got: %d", i, c.want, got)
+		}
+	}
+}
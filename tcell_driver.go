@@ -46,9 +46,9 @@ func tcellInitSimulation() error {
 
 // tcellSetCell sets the character cell at a given location to the given
 // content (rune) and attributes using provided OutputMode
-func tcellSetCell(x, y int, ch rune, fg, bg Attribute, omode OutputMode) {
+func tcellSetCell(x, y int, ch rune, fg, bg Attribute, omode OutputMode, combining ...rune) {
 	st := getTcellStyle(fg, bg, omode)
-	screen.SetContent(x, y, ch, nil, st)
+	screen.SetContent(x, y, ch, combining, st)
 }
 
 // getTcellStyle creates tcell.Style from Attributes
@@ -125,6 +125,8 @@ const (
 	eventError
 	eventRaw
 	eventTime
+	eventPasteStart
+	eventPasteEnd
 )
 
 var (
@@ -230,6 +232,11 @@ func pollEvent() gocuiEvent {
 		}
 	case *tcell.EventTime:
 		return gocuiEvent{Type: eventTime}
+	case *tcell.EventPaste:
+		if tev.Start() {
+			return gocuiEvent{Type: eventPasteStart}
+		}
+		return gocuiEvent{Type: eventPasteEnd}
 	default:
 		return gocuiEvent{Type: eventNone}
 	}
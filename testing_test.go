@@ -24,6 +24,7 @@ func TestTestingScreenReturnsCorrectContent(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	t.Cleanup(g.Close)
 	g.SetManagerFunc(func(g *Gui) error {
 		maxX, maxY := g.Size()
 		if v, err := g.SetView(viewName, maxX/2-7, maxY/2, maxX/2+7, maxY/2+2, 0); err != nil {
@@ -85,6 +86,7 @@ func TestTestingScreenMultipleKeys(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	t.Cleanup(g.Close)
 	g.SetManagerFunc(func(g *Gui) error {
 		maxX, maxY := g.Size()
 		if v, err := g.SetView(viewName, maxX/2-7, maxY/2, maxX/2+7, maxY/2+2, 0); err != nil {
@@ -181,6 +183,7 @@ func TestTestingScreenParallelKeys(t *testing.T) {
 	if err != nil {
 		t.Error(err)
 	}
+	t.Cleanup(g.Close)
 	g.SetManagerFunc(func(g *Gui) error {
 		maxX, maxY := g.Size()
 		if v, err := g.SetView(viewName, maxX/2-7, maxY/2, maxX/2+7, maxY/2+2, 0); err != nil {
@@ -275,3 +278,116 @@ func assertView(t *testing.T, ts TestingScreen, viewName, content string) {
 		}
 	}
 }
+
+func TestScreenBuffer(t *testing.T) {
+	g, err := NewGui(OutputSimulator, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+	g.SetManagerFunc(func(g *Gui) error {
+		if v, err := g.SetView("test", 1, 0, 7, 3, 0); err != nil {
+			if !errors.Is(err, ErrUnknownView) {
+				return err
+			}
+			v.Title = "t"
+			fmt.Fprint(v, "hello world\nfoo")
+			v.SetOrigin(6, 0)
+		}
+		return nil
+	})
+
+	testingScreen := g.GetTestingScreen()
+	cleanup := testingScreen.StartGui()
+	defer cleanup()
+
+	// The size is read in the main loop, which updates it on each flush
+	rows := make(chan [][]rune, 1)
+	maxY := 0
+	g.Update(func(g *Gui) error {
+		_, maxY = g.Size()
+		rows <- g.ScreenBuffer()
+		return nil
+	})
+	buffer := <-rows
+
+	want := []string{
+		" ┌─t───┐ ",
+		" │world│ ",
+		" │     │ ",
+		" └─────┘ ",
+		"         ",
+	}
+	if len(buffer) != maxY {
+		t.Fatalf("Expected %d rows got: %d", maxY, len(buffer))
+	}
+	for y, w := range want {
+		if got := string(buffer[y][:len([]rune(w))]); got != w {
+			t.Errorf("Expected row %d to be: %q got: %q", y, w, got)
+		}
+	}
+}
+
+func TestSimulateKeypress(t *testing.T) {
+	g, err := NewGui(OutputSimulator, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(g.Close)
+	input, err := g.SetView("input", 0, 0, 20, 2, 0)
+	if !errors.Is(err, ErrUnknownView) {
+		t.Fatal(err)
+	}
+	input.Editable = true
+	list, err := g.SetView("list", 0, 3, 20, 5, 0)
+	if !errors.Is(err, ErrUnknownView) {
+		t.Fatal(err)
+	}
+	if _, err := g.SetCurrentView("input"); err != nil {
+		t.Fatal(err)
+	}
+
+	var calls []string
+	handler := func(name string) func(*Gui, *View) error {
+		return func(g *Gui, v *View) error {
+			calls = append(calls, name+":"+v.Name())
+			return nil
+		}
+	}
+	if err := g.SetKeybinding("list", 'j', ModNone, handler("down")); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetKeybinding("", KeyCtrlC, ModNone, handler("quit")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.SimulateKeypress("", 0, 'h', ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SimulateKeypress("", 0, 'i', ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SimulateKeypress("input", 0, 'j', ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if got := input.Buffer(); got != "hij" {
+		t.Errorf("Expected the editor to write %q got: %q", "hij", got)
+	}
+
+	if err := g.SimulateKeypress("list", 0, 'j', ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SimulateKeypress("", KeyCtrlC, 0, ModNone); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(calls, ","), "down:list,quit:input"; got != want {
+		t.Errorf("Expected handler calls %q got: %q", want, got)
+	}
+	if list.Buffer() != "" {
+		t.Errorf("Expected the list view to stay empty got: %q", list.Buffer())
+	}
+
+	if err := g.SimulateKeypress("missing", 0, 'j', ModNone); err != ErrUnknownView {
+		t.Errorf("Expected ErrUnknownView for a missing view got: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "time"
+
+// SetTickInterval makes the main loop call OnTick every d, e.g. to animate a
+// spinner, and redraw the GUI afterwards like Update does. Ticks are dropped
+// if the main loop is too busy to handle them on time. A duration of 0 or
+// less stops the ticks. SetTickInterval can be called from any goroutine.
+func (g *Gui) SetTickInterval(d time.Duration) {
+	g.tickMutex.Lock()
+	defer g.tickMutex.Unlock()
+
+	if g.tickStop != nil {
+		close(g.tickStop)
+		g.tickStop = nil
+	}
+	if d <= 0 {
+		return
+	}
+
+	stop := make(chan struct{})
+	g.tickStop = stop
+	go func() {
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-stop:
+				return
+			}
+			select {
+			case g.userEvents <- userEvent{f: g.tick}:
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// tick calls OnTick, it's run by the main loop.
+func (g *Gui) tick(*Gui) error {
+	if g.OnTick == nil {
+		return nil
+	}
+	return g.OnTick(g)
+}
@@ -0,0 +1,262 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+// defaultUndoLimit is the default value of View.UndoLimit.
+const defaultUndoLimit = 100
+
+// cursorState holds the cursor position and the view offsets.
+type cursorState struct {
+	cx, cy, ox, oy int
+}
+
+// lineChange describes the replacement of the lines [y, y+len(old)) of the
+// view's internal buffer by n new lines.
+type lineChange struct {
+	y   int
+	old [][]cell
+	n   int
+
+	// new holds the inserted lines, it's filled when the change is undone
+	// so it can be redone.
+	new [][]cell
+}
+
+// undoEntry is a single undo step, it contains all the changes made by an
+// edit and the cursor state before and after the edit.
+type undoEntry struct {
+	changes       []lineChange
+	before, after cursorState
+
+	// coalesce is true if the entry can be merged with the following one
+	// (consecutive character insertions).
+	coalesce bool
+}
+
+// beginEdit starts an edit of the view's internal buffer. Edits can be
+// nested, all the changes made until the outermost edit ends form a single
+// undo step.
+func (v *View) beginEdit() {
+	if v.editDepth == 0 {
+		v.editStart = v.cursorState()
+		if v.UndoLimit > 0 {
+			v.editUnit = &undoEntry{before: v.editStart}
+		}
+	}
+	v.editDepth++
+}
+
+// endEdit ends an edit started with beginEdit.
+func (v *View) endEdit() {
+	v.editDepth--
+	if v.editDepth > 0 {
+		return
+	}
+
+	if unit := v.editUnit; unit != nil && len(unit.changes) > 0 {
+		unit.after = v.cursorState()
+		v.pushUndo(unit)
+	}
+	v.editUnit = nil
+	v.cursorMoved(v.editStart.cx, v.editStart.cy)
+	if v.editChanged {
+		v.editChanged = false
+		v.bufferChanged()
+	}
+}
+
+// Modified reports whether the view's internal buffer changed since the
+// view was created or since the last call to ClearModified, e.g. to show
+// that a file has unsaved changes. It's set by the edits, the writes and
+// Undo and Redo, but not by the rendering.
+func (v *View) Modified() bool {
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	return v.modified
+}
+
+// ClearModified resets the flag returned by Modified, e.g. once the content
+// of the view was saved.
+func (v *View) ClearModified() {
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	v.modified = false
+}
+
+// bufferChanged sets the modified flag and calls OnChange and OnWrite.
+func (v *View) bufferChanged() {
+	v.modified = true
+	if v.OnChange != nil {
+		v.OnChange(v)
+	}
+	v.bufferWritten()
+}
+
+// bufferWritten restores the cursor kept by ClearKeepCursor and calls
+// OnWrite. The writers defer it before locking the buffer, so it's called
+// once the buffer is unlocked.
+func (v *View) bufferWritten() {
+	v.restoreCursor()
+	if v.OnWrite != nil {
+		v.OnWrite(v)
+	}
+}
+
+// pushUndo adds an entry to the undo history and invalidates the redo
+// history.
+func (v *View) pushUndo(e *undoEntry) {
+	v.redoStack = nil
+
+	if n := len(v.undoStack); n > 0 && e.coalesce {
+		last := v.undoStack[n-1]
+		if last.coalesce && last.after == e.before {
+			last.changes = append(last.changes, e.changes...)
+			last.after = e.after
+			return
+		}
+	}
+
+	v.undoStack = append(v.undoStack, e)
+	if over := len(v.undoStack) - v.UndoLimit; over > 0 {
+		v.undoStack = v.undoStack[over:]
+	}
+}
+
+// recordChange must be called before the lines [y, y+nOld) of the view's
+// internal buffer are replaced by nNew lines. Changes made outside of an edit
+// discard the undo history, as it no longer matches the buffer.
+func (v *View) recordChange(y, nOld, nNew int) {
+	v.hasGoal = false
+	v.hasMaxWidth = false
+	if v.editDepth > 0 {
+		v.editChanged = true
+	}
+	if v.editUnit == nil {
+		v.resetUndo()
+		return
+	}
+	v.editUnit.changes = append(v.editUnit.changes, lineChange{
+		y:   y,
+		old: copyLines(v.lines.slice(y, y+nOld)),
+		n:   nNew,
+	})
+}
+
+// resetUndo discards the undo and redo history.
+func (v *View) resetUndo() {
+	v.undoStack = nil
+	v.redoStack = nil
+}
+
+// Undo reverts the last edit made with the Edit* helpers, restoring the
+// buffer, the cursor and the view offsets. It does nothing if there is no
+// edit to revert.
+func (v *View) Undo() {
+	n := len(v.undoStack)
+	if n == 0 {
+		return
+	}
+	e := v.undoStack[n-1]
+	v.undoStack = v.undoStack[:n-1]
+	if !v.canUndo(e) {
+		v.resetUndo()
+		return
+	}
+
+	for i := len(e.changes) - 1; i >= 0; i-- {
+		c := &e.changes[i]
+		c.new = copyLines(v.lines.slice(c.y, c.y+c.n))
+		v.replaceLines(c.y, c.n, copyLines(c.old))
+	}
+	v.setCursorState(e.before)
+
+	e.coalesce = false
+	v.redoStack = append(v.redoStack, e)
+	v.bufferChanged()
+}
+
+// Redo re-applies the last edit reverted by Undo. It does nothing if there is
+// no edit to re-apply. Any new edit invalidates the redo history.
+func (v *View) Redo() {
+	n := len(v.redoStack)
+	if n == 0 {
+		return
+	}
+	e := v.redoStack[n-1]
+	v.redoStack = v.redoStack[:n-1]
+	if !v.canRedo(e) {
+		v.resetUndo()
+		return
+	}
+
+	for i := range e.changes {
+		c := &e.changes[i]
+		v.replaceLines(c.y, len(c.old), copyLines(c.new))
+	}
+	v.setCursorState(e.after)
+
+	v.undoStack = append(v.undoStack, e)
+	v.bufferChanged()
+}
+
+// canUndo reports whether all the changes of e fit in the view's internal
+// buffer when they are undone, so an entry that doesn't match the buffer
+// anymore isn't partly applied.
+func (v *View) canUndo(e *undoEntry) bool {
+	n := v.lines.len()
+	for i := len(e.changes) - 1; i >= 0; i-- {
+		c := &e.changes[i]
+		if c.y+c.n > n {
+			return false
+		}
+		n += len(c.old) - c.n
+	}
+	return true
+}
+
+// canRedo is like canUndo for redoing the changes of e.
+func (v *View) canRedo(e *undoEntry) bool {
+	n := v.lines.len()
+	for i := range e.changes {
+		c := &e.changes[i]
+		if c.y+len(c.old) > n {
+			return false
+		}
+		n += c.n - len(c.old)
+	}
+	return true
+}
+
+// cursorState returns the current cursor position and view offsets.
+func (v *View) cursorState() cursorState {
+	return cursorState{cx: v.cx, cy: v.cy, ox: v.ox, oy: v.oy}
+}
+
+// setCursorState restores the cursor position and view offsets.
+func (v *View) setCursorState(s cursorState) {
+	v.tainted = true
+	v.ox, v.oy = s.ox, s.oy
+	v.setCursor(s.cx, s.cy)
+}
+
+// replaceLines replaces the lines [y, y+n) of the view's internal buffer
+// by the given lines.
+func (v *View) replaceLines(y, n int, lines [][]cell) {
+	v.tainted = true
+	v.hasMaxWidth = false
+	v.lines.remove(y, n)
+	v.lines.insert(y, lines...)
+}
+
+// copyLines returns a deep copy of the given lines.
+func copyLines(lines [][]cell) [][]cell {
+	cp := make([][]cell, len(lines))
+	for i, l := range lines {
+		if l != nil {
+			cp[i] = append([]cell{}, l...)
+		}
+	}
+	return cp
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "testing"
+
+// typeString feeds the runes of s to the view's editor.
+func typeString(v *View, s string) {
+	for _, r := range s {
+		v.Editor.Edit(v, 0, r, ModNone)
+	}
+}
+
+func TestUndoTyping(t *testing.T) {
+	v := newTestView(20, 5, "foo")
+	v.SetCursor(3, 0)
+
+	typeString(v, "bar")
+	v.Editor.Edit(v, KeySpace, 0, ModNone)
+	typeString(v, "baz")
+	assertBuffer(t, v, 10, 0, "foobar baz")
+
+	v.Undo()
+	assertBuffer(t, v, 7, 0, "foobar ")
+	v.Undo()
+	assertBuffer(t, v, 6, 0, "foobar")
+	v.Undo()
+	assertBuffer(t, v, 3, 0, "foo")
+	v.Undo()
+	assertBuffer(t, v, 3, 0, "foo")
+
+	v.Redo()
+	assertBuffer(t, v, 6, 0, "foobar")
+	v.Redo()
+	assertBuffer(t, v, 7, 0, "foobar ")
+}
+
+func TestUndoBackspaceAndNewLine(t *testing.T) {
+	v := newTestView(20, 5, "hello", "world")
+	v.SetCursor(0, 1)
+
+	v.Editor.Edit(v, KeyBackspace2, 0, ModNone)
+	assertBuffer(t, v, 5, 0, "helloworld")
+	v.Editor.Edit(v, KeyBackspace2, 0, ModNone)
+	assertBuffer(t, v, 4, 0, "hellworld")
+	v.Editor.Edit(v, KeyEnter, 0, ModNone)
+	assertBuffer(t, v, 0, 1, "hell", "world")
+
+	v.Undo()
+	assertBuffer(t, v, 4, 0, "hellworld")
+	v.Undo()
+	assertBuffer(t, v, 5, 0, "helloworld")
+	v.Undo()
+	assertBuffer(t, v, 0, 1, "hello", "world")
+
+	v.Redo()
+	v.Redo()
+	v.Redo()
+	assertBuffer(t, v, 0, 1, "hell", "world")
+}
+
+func TestUndoRedoInvalidation(t *testing.T) {
+	v := newTestView(20, 5, "")
+	typeString(v, "abc")
+	v.Undo()
+	assertBuffer(t, v, 0, 0, "")
+
+	typeString(v, "x")
+	v.Redo()
+	assertBuffer(t, v, 1, 0, "x")
+}
+
+func TestUndoOutOfRange(t *testing.T) {
+	v := newTestView(20, 5, "a", "b")
+	line := func(r rune) [][]cell {
+		return [][]cell{{{chr: r}}}
+	}
+
+	// The first change applied fits in the buffer but not the second one
+	v.undoStack = []*undoEntry{{changes: []lineChange{
+		{y: 3, old: line('x'), n: 1},
+		{y: 0, old: line('y'), n: 1},
+	}}}
+	v.Undo()
+	assertBuffer(t, v, 0, 0, "a", "b")
+	if len(v.undoStack) != 0 || len(v.redoStack) != 0 {
+		t.Errorf("Expected the history to be reset after Undo")
+	}
+
+	v.redoStack = []*undoEntry{{changes: []lineChange{
+		{y: 0, old: line('a'), n: 1, new: line('y')},
+		{y: 3, old: line('x'), n: 1, new: line('z')},
+	}}}
+	v.Redo()
+	assertBuffer(t, v, 0, 0, "a", "b")
+	if len(v.undoStack) != 0 || len(v.redoStack) != 0 {
+		t.Errorf("Expected the history to be reset after Redo")
+	}
+}
+
+func TestUndoLimit(t *testing.T) {
+	v := newTestView(20, 5, "")
+	v.UndoLimit = 2
+	for i := 0; i < 3; i++ {
+		v.Editor.Edit(v, KeySpace, 0, ModNone)
+	}
+	v.Undo()
+	v.Undo()
+	v.Undo()
+	assertBuffer(t, v, 1, 0, " ")
+
+	v.UndoLimit = 0
+	v.Editor.Edit(v, KeySpace, 0, ModNone)
+	v.Undo()
+	assertBuffer(t, v, 2, 0, "  ")
+}
+
+func TestModified(t *testing.T) {
+	v := newTestView(20, 5, "foo")
+	if v.Modified() {
+		t.Fatal("Expected a new view not to be modified")
+	}
+
+	// Moves and rendering don't modify the buffer
+	v.MoveCursor(1, 0)
+	drawTestView(t, v)
+	v.SetCursor(0, 0)
+	v.EditDelete(true)
+	if v.Modified() {
+		t.Error("Expected no modification without a change")
+	}
+
+	for _, tt := range []struct {
+		name string
+		f    func()
+	}{
+		{"EditWrite", func() { v.EditWrite('a') }},
+		{"EditDeleteLine", func() { v.EditDeleteLine() }},
+		{"Undo", func() { v.Undo() }},
+		{"Redo", func() { v.Redo() }},
+		{"Write", func() { _, _ = v.Write([]byte("bar")) }},
+		{"AppendLine", func() { v.AppendLine("baz") }},
+		{"SetContent", func() { v.SetContent([]string{"qux"}) }},
+		{"SetLine", func() { _ = v.SetLine(0, "quux") }},
+		{"Clear", func() { v.Clear() }},
+	} {
+		v.ClearModified()
+		if v.Modified() {
+			t.Fatalf("Expected ClearModified to reset the flag before %s", tt.name)
+		}
+		tt.f()
+		if !v.Modified() {
+			t.Errorf("Expected %s to set the flag", tt.name)
+		}
+	}
+}
@@ -8,8 +8,10 @@ import (
 	"bytes"
 	"errors"
 	"io"
+	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/mattn/go-runewidth"
@@ -23,6 +25,43 @@ const (
 	RIGHT  = 8 // view is overlapping at right edge
 )
 
+// defaultTabWidth is the default value of View.TabWidth.
+const defaultTabWidth = 8
+
+// Alignment is the horizontal alignment of the lines of a view.
+type Alignment int
+
+// Horizontal alignments.
+const (
+	AlignLeft Alignment = iota
+	AlignCenter
+	AlignRight
+)
+
+// VerticalAlignment is the vertical alignment of the content of a view.
+type VerticalAlignment int
+
+// Vertical alignments.
+const (
+	AlignTop VerticalAlignment = iota
+	AlignMiddle
+	AlignBottom
+)
+
+// FrameSides are the sides of the frame of a view.
+type FrameSides struct {
+	Top, Bottom, Left, Right bool
+}
+
+// Frame styles that can be used as View.FrameRunes. The slices are shared,
+// copy them before changing some of their runes.
+var (
+	FrameStyleSingle  = []rune{'─', '│', '┌', '┐', '└', '┘', '├', '┤', '┬', '┴', '┼'}
+	FrameStyleDouble  = []rune{'═', '║', '╔', '╗', '╚', '╝', '╠', '╣', '╦', '╩', '╬'}
+	FrameStyleRounded = []rune{'─', '│', '╭', '╮', '╰', '╯', '├', '┤', '┬', '┴', '┼'}
+	FrameStyleASCII   = []rune{'-', '|', '+', '+', '+', '+', '+', '+', '+', '+', '+'}
+)
+
 var (
 	// ErrInvalidPoint is returned when client passed invalid coordinates of a cell.
 	// Most likely client has passed negative coordinates of a cell.
@@ -31,32 +70,94 @@ var (
 
 // A View is a window. It maintains its own internal buffer and cursor
 // position.
+//
+// Write, WriteRunes, WriteString, WriteStyled, AppendLine, Clear, SetContent,
+// SetCell and FillRect can be called from any goroutine, e.g. to log to a
+// view from a background task. They lock the view's buffer, as do drawing the
+// view and reading its content with Buffer, BufferLines, Lines, Line, Cell,
+// ViewBuffer and ViewBufferLines, so concurrent calls never corrupt it. The
+// other methods and fields, the Edit* helpers and the cursor in particular,
+// are not synchronized: call them from the main loop, e.g. with Gui.Update or
+// Gui.UpdateAsync.
 type View struct {
 	name           string
-	x0, y0, x1, y1 int      // left top right bottom
-	ox, oy         int      // view offsets
-	cx, cy         int      // cursor position
-	rx, ry         int      // Read() offsets
-	wx, wy         int      // Write() offsets
-	lines          [][]cell // All the data
+	x0, y0, x1, y1 int        // left top right bottom
+	ox, oy         int        // view offsets
+	cx, cy         int        // cursor position
+	rx, ry         int        // Read() offsets
+	wx, wy         int        // Write() offsets
+	lines          lineBuffer // All the data
 	outMode        OutputMode
 
 	// readBuffer is used for storing unread bytes
 	readBuffer []byte
 
+	// partialRune is the incomplete UTF-8 encoding that ended the last Write
+	partialRune []byte
+
+	// keptCursor is the cursor position restored by the writes that follow
+	// ClearKeepCursor
+	keptCursor *keptCursor
+
 	// tained is true if the viewLines must be updated
 	tainted bool
 
+	// modified is true if the buffer changed since the last ClearModified
+	modified bool
+
+	// maxWidth is the width of the widest line computed by maxLineWidth
+	// with the tab width maxWidthTab, it's valid while hasMaxWidth is true.
+	// The changes of the buffer reset hasMaxWidth.
+	maxWidth, maxWidthTab int
+	hasMaxWidth           bool
+
 	// contentCache is the content the frame
 	// if a redraw is request with tainted is false this will be used to draw the frame
+	// drawnState are the settings the cache was drawn with, it's redrawn if they change
 	contentCache []cellCache
+	drawnState   renderState
 
-	// writeMutex protects locks the write process
+	// thumbStart and thumbSize are the position of the scrollbar thumb
+	// computed by draw, thumbSize is 0 if the content fits in the view
+	thumbStart, thumbSize int
+
+	// writeMutex protects the internal buffer against concurrent writes,
+	// reads and draws, see the View documentation
 	writeMutex sync.Mutex
 
 	// ei is used to decode ESC sequences on Write
 	ei *escapeInterpreter
 
+	// undoStack and redoStack hold the edit history used by Undo and Redo
+	undoStack, redoStack []*undoEntry
+
+	// editDepth is the nesting level of the current edit, editStart is the
+	// cursor state when it started, editUnit collects its changes and
+	// editChanged is true once it changed the buffer
+	editDepth   int
+	editStart   cursorState
+	editUnit    *undoEntry
+	editChanged bool
+
+	// selection is the selected range of the buffer, nil if nothing is selected
+	selection *selection
+
+	// dragX and dragY are the anchor of the selection made with the mouse
+	dragX, dragY int
+
+	// goalColumn is the column vertical cursor moves try to reach, if
+	// hasGoal is true
+	goalColumn int
+	hasGoal    bool
+
+	// killRing holds the text removed by the kill commands
+	killRing []string
+
+	// searchPattern is the text highlighted by SetSearchHighlight and
+	// searchMatches holds its occurrences, by line, since the last draw
+	searchPattern []rune
+	searchMatches [][]searchMatch
+
 	// Visible specifies whether the view is visible.
 	Visible bool
 
@@ -65,9 +166,52 @@ type View struct {
 	BgColor, FgColor Attribute
 
 	// SelBgColor and SelFgColor are used to configure the background and
-	// foreground colors of the selected line, when it is highlighted.
+	// foreground colors of the selected line, when it is highlighted, and of
+	// the text selected with SetSelection.
 	SelBgColor, SelFgColor Attribute
 
+	// SearchBgColor and SearchFgColor are used to configure the background
+	// and foreground colors of the text highlighted with SetSearchHighlight.
+	SearchBgColor, SearchFgColor Attribute
+
+	// Alignment allows to center the lines of the view or to align them to
+	// the right, e.g. for a banner. It's ignored for editable views and for
+	// the lines wider than the view.
+	Alignment Alignment
+
+	// VAlign allows to center the content of the view vertically or to
+	// align it to the bottom, if it's shorter than the view. Like
+	// Alignment, it's ignored for editable views.
+	VAlign VerticalAlignment
+
+	// Placeholder is the text displayed while the view's internal buffer is
+	// empty, e.g. a hint in an input field. It isn't part of the buffer.
+	// PlaceholderFgColor is its color, dimmed by default.
+	Placeholder        string
+	PlaceholderFgColor Attribute
+
+	// If ShowLineNumbers is true, the numbers of the lines of the buffer are
+	// drawn right-aligned in a gutter, on the left of the content area. The
+	// gutter is as wide as the largest number plus a space, Size returns
+	// the width of the content area without it. LineNumberFgColor is the
+	// color of the numbers, dimmed by default.
+	ShowLineNumbers   bool
+	LineNumberFgColor Attribute
+
+	// If RelativeLineNumbers and ShowLineNumbers are true, the gutter shows
+	// the distance of each line to the line of the cursor, like the
+	// relativenumber option of Vim. The line of the cursor shows its number.
+	RelativeLineNumbers bool
+
+	// If ShowWhitespace is true, spaces are drawn as '·' and tabs as '→'
+	// followed by spaces up to the next tab stop, using WhitespaceFgColor,
+	// dimmed by default. The whitespace at the end of the lines is drawn
+	// on TrailingWhitespaceBgColor if it's not ColorDefault. The buffer
+	// isn't changed.
+	ShowWhitespace            bool
+	WhitespaceFgColor         Attribute
+	TrailingWhitespaceBgColor Attribute
+
 	// If Editable is true, keystrokes will be added to the view's internal
 	// buffer at the cursor position.
 	Editable bool
@@ -77,19 +221,126 @@ type View struct {
 	// default.
 	Editor Editor
 
+	// If EditorFallthrough is true and Editor is a FallthroughEditor, the
+	// keys are passed to the editor first and the ones it doesn't handle
+	// go through the keybindings.
+	EditorFallthrough bool
+
 	// Overwrite enables or disables the overwrite mode of the view.
 	Overwrite bool
 
+	// If MaxLength is greater than 0, the Edit* helpers don't write more
+	// than MaxLength runes in the view, line breaks included. OnMaxLength
+	// is called when a rune is refused, e.g. to beep.
+	MaxLength   int
+	OnMaxLength func(v *View)
+
+	// If MaxColumn is greater than 0, EditWrite doesn't write at or after
+	// the MaxColumn-th cell of a line and doesn't make a line longer than
+	// MaxColumn cells, e.g. for fixed width form fields. OnMaxLength is
+	// called when a rune is refused because of MaxColumn too.
+	MaxColumn int
+
+	// If SingleLine is true, the view is a one line input: EditNewLine calls
+	// OnSubmit instead, the line breaks written with EditWriteString are
+	// replaced by spaces and MoveCursor doesn't move the cursor vertically.
+	SingleLine bool
+
+	// OnChange is called after each edit of the buffer made with the Edit*
+	// helpers, Undo or Redo. OnSubmit is called when EditNewLine is called
+	// in SingleLine mode, e.g. when Enter is pressed.
+	OnChange func(v *View)
+	OnSubmit func(v *View)
+
+	// OnWrite is called after any change of the buffer: the writes made with
+	// Write, WriteRunes, WriteString, WriteStyled, AppendLine, SetContent,
+	// SetLine and Clear, and the changes that call OnChange. It's called
+	// synchronously, once the change is complete, by the goroutine that made
+	// it, e.g. to update a preview of the view.
+	OnWrite func(v *View)
+
+	// OnPaste is called with the text pasted in the terminal when the view
+	// is the current view and Gui.Paste is true. If it's nil, the text is
+	// written in the view if it's editable.
+	OnPaste func(v *View, text string)
+
+	// OnCursorMove is called when the cursor position changes, with the
+	// previous and the new position. An edit moving the cursor several times
+	// calls it once.
+	OnCursorMove func(v *View, oldX, oldY, newX, newY int)
+
+	// UndoLimit is the maximum number of edits that can be reverted with
+	// Undo. Zero or a negative value disables the undo history.
+	UndoLimit int
+
+	// TabWidth is the distance between two tab stops. A tab is stored as a
+	// single '\t' rune and it's displayed up to the next tab stop.
+	TabWidth int
+
+	// If AutoIndent is true, a new line inserted by EditNewLine starts
+	// with the same indentation as the current line.
+	AutoIndent bool
+
+	// If AutoPair is true, the default editor writes the closing character
+	// of the brackets and quotes typed, types over a closing character
+	// already at the cursor, and deletes both characters of an empty pair
+	// on backspace.
+	AutoPair bool
+
+	// If ExpandTabs is true, the tab key inserts spaces up to the next tab
+	// stop instead of a '\t' rune.
+	ExpandTabs bool
+
+	// WordDelimiter reports whether a rune separates words for the
+	// word-wise editing helpers and cursor motions. DefaultWordDelimiter is
+	// used if it's nil.
+	WordDelimiter func(r rune) bool
+
 	// If Highlight is true, Sel{Bg,Fg}Colors will be used
 	// for the line under the cursor position.
 	Highlight bool
 
+	// If HighlightCurrentLine is true, all the rows of the line under the
+	// cursor are drawn on CurrentLineBgColor, across the whole width of the
+	// view. Contrary to Highlight, the colors of the text are kept.
+	HighlightCurrentLine bool
+	CurrentLineBgColor   Attribute
+
+	// If HighlightMatchingBracket is true and the cursor is on a bracket
+	// that has a match, as returned by MatchingBracket, both brackets are
+	// drawn using MatchingBracket{Bg,Fg}Color. The foreground is reversed
+	// by default.
+	HighlightMatchingBracket bool
+	MatchingBracketFgColor   Attribute
+	MatchingBracketBgColor   Attribute
+
 	// If Frame is true, a border will be drawn around the view.
 	Frame bool
 
+	// FrameSides allows to choose the sides of the frame, all of them by
+	// default. The content area of the view extends over the sides that are
+	// turned off, e.g. to make adjacent views look seamless, whether Frame
+	// is true or not.
+	FrameSides FrameSides
+
+	// If ShowScrollbar is true and the content of the view doesn't fit in
+	// its height, a scrollbar thumb is drawn on the right side of the frame
+	// to show which part of the content is visible.
+	ShowScrollbar bool
+
 	// FrameColor allow to configure the color of the Frame when it is not highlighted.
 	FrameColor Attribute
 
+	// FrameBgColor allows to configure the background color of the Frame,
+	// title and subtitle included. The background color of the Gui is used
+	// if it's ColorDefault.
+	FrameBgColor Attribute
+
+	// FocusedFrameFgColor allows to configure the color of the Frame when
+	// the view is the current view of the Gui. Unless it's ColorDefault, it
+	// takes precedence over FrameColor and the SelFrameColor of the Gui.
+	FocusedFrameFgColor Attribute
+
 	// FrameRunes allows to define custom runes for the frame edges.
 	// The rune slice can be defined with 3 different lengths.
 	// If slice doesn't match these lengths, default runes will be used instead of missing one.
@@ -103,6 +354,8 @@ type View struct {
 	// 11 runes which can be used with `gocui.Gui.SupportOverlaps` property.
 	//  []rune{'─', '│', '┌', '┐', '└', '┘', '├', '┤', '┬', '┴', '┼'}
 	//  []rune{'═','║','╔','╗','╚','╝','╠','╣','╦','╩','╬'}
+	// FrameStyleSingle, FrameStyleDouble, FrameStyleRounded and
+	// FrameStyleASCII are ready to use sets of 11 runes.
 	FrameRunes []rune
 
 	// If Wrap is true, the content that is written to this View is
@@ -110,21 +363,68 @@ type View struct {
 	// view's x-origin will be ignored.
 	Wrap bool
 
+	// If WrapWords is true, wrapped lines are broken after the last
+	// whitespace that fits in the view width, so words are not split. Words
+	// longer than the view width are still split.
+	WrapWords bool
+
+	// If EllipsisOverflow is true, the last visible column of the lines
+	// that go past the right edge of the view shows '…' instead of their
+	// content. It's ignored if Wrap or Editable is true.
+	EllipsisOverflow bool
+
 	// If Autoscroll is true, the View will automatically scroll down when the
 	// text overflows. If true the view's y-origin will be ignored.
 	Autoscroll bool
 
+	// If ScrollMargin is greater than 0, the view scrolls when the cursor
+	// is moved so at least ScrollMargin rows stay visible above and below
+	// it, like the scrolloff option of Vim. The margin is reduced if the
+	// view is too small and the cursor can still reach the first and the
+	// last rows of the buffer.
+	ScrollMargin int
+
+	// SideScrollMargin is the number of columns kept visible on the left
+	// and the right of the cursor when it's moved and Wrap is false, like
+	// the sidescrolloff option of Vim. The margin is reduced if the view is
+	// too narrow and the cursor can still reach the start and the end of the
+	// lines. If it's 0, only 2 columns are kept visible on the left of the
+	// cursor, to show some of the text hidden at the start of the line.
+	SideScrollMargin int
+
+	// If MaxLines is greater than 0, the oldest lines are dropped when the
+	// text written with Write, WriteRunes, WriteString, WriteStyled or
+	// AppendLine makes the view hold more than MaxLines lines. The empty
+	// line left by a trailing newline isn't counted. The cursor, the origin
+	// and the selection are moved so they stay on the same text.
+	MaxLines int
+
 	// If Frame is true, Title allows to configure a title for the view.
 	Title string
 
-	// TitleColor allow to configure the color of title and subtitle for the view.
+	// TitleColor allow to configure the color of title, subtitle and footer for the view.
 	TitleColor Attribute
 
 	// If Frame is true, Subtitle allows to configure a subtitle for the view.
+	// It's drawn at the opposite end of the top border to the title.
 	Subtitle string
 
-	// If Mask is true, the View will display the mask instead of the real
-	// content
+	// TitleAlignment allows to center the title in the top border or to
+	// align it to the right. The title and the subtitle are truncated if the
+	// view is too narrow, the subtitle first.
+	TitleAlignment Alignment
+
+	// If Frame is true, Footer allows to configure a text drawn in the bottom
+	// border of the view, e.g. keybinding hints or the scroll position. It's
+	// aligned according to FooterAlignment and truncated if the view is too
+	// narrow.
+	Footer          string
+	FooterAlignment Alignment
+
+	// If Mask is not zero, the View displays it instead of every rune of its
+	// content, e.g. '*' or '•' for a password field. Each rune, even a tab
+	// or a wide one, is drawn as one mask rune. The content itself isn't
+	// changed.
 	Mask rune
 
 	// Overlaps describes which edges are overlapping with another view's edges
@@ -144,12 +444,18 @@ type View struct {
 type cell struct {
 	chr              rune
 	bgColor, fgColor Attribute
+
+	// combining holds the combining marks drawn on top of chr. The slice
+	// is shared by the copies of the cell and must not be modified.
+	combining []rune
 }
 
 type cellCache struct {
 	chr              rune
+	combining        []rune
 	bgColor, fgColor Attribute
 	x, y             int
+	gutter           bool
 }
 
 type lineType []cell
@@ -158,7 +464,7 @@ type lineType []cell
 func (l lineType) String() string {
 	str := ""
 	for _, c := range l {
-		str += string(c.chr)
+		str += string(c.chr) + string(c.combining)
 	}
 	return str
 }
@@ -166,23 +472,35 @@ func (l lineType) String() string {
 // newView returns a new View object.
 func (g *Gui) newView(name string, x0, y0, x1, y1 int, mode OutputMode) *View {
 	v := &View{
-		name:    name,
-		x0:      x0,
-		y0:      y0,
-		x1:      x1,
-		y1:      y1,
-		Visible: true,
-		Frame:   true,
-		Editor:  DefaultEditor,
-		tainted: true,
-		outMode: mode,
-		ei:      newEscapeInterpreter(mode),
-		gui:     g,
+		name:      name,
+		x0:        x0,
+		y0:        y0,
+		x1:        x1,
+		y1:        y1,
+		Visible:   true,
+		Frame:     true,
+		Editor:    DefaultEditor,
+		UndoLimit: defaultUndoLimit,
+		TabWidth:  defaultTabWidth,
+		tainted:   true,
+		outMode:   mode,
+		ei:        newEscapeInterpreter(mode),
+		gui:       g,
 	}
 
 	v.FgColor, v.BgColor = ColorDefault, ColorDefault
 	v.SelFgColor, v.SelBgColor = ColorDefault, ColorDefault
+	v.SearchFgColor, v.SearchBgColor = ColorDefault, ColorDefault
+	v.PlaceholderFgColor = ColorDefault | AttrDim
+	v.LineNumberFgColor = ColorDefault | AttrDim
+	v.WhitespaceFgColor = ColorDefault | AttrDim
+	v.TrailingWhitespaceBgColor = ColorDefault
+	v.CurrentLineBgColor = ColorDefault
+	v.MatchingBracketFgColor = ColorDefault | AttrReverse
+	v.MatchingBracketBgColor = ColorDefault
+	v.FrameSides = FrameSides{Top: true, Bottom: true, Left: true, Right: true}
 	v.TitleColor, v.FrameColor = ColorDefault, ColorDefault
+	v.FrameBgColor, v.FocusedFrameFgColor = ColorDefault, ColorDefault
 	return v
 }
 
@@ -191,9 +509,107 @@ func (v *View) Dimensions() (int, int, int, int) {
 	return v.x0, v.y0, v.x1, v.y1
 }
 
-// Size returns the number of visible columns and rows in the View.
+// Size returns the number of visible columns and rows in the View. The
+// line numbers gutter isn't counted.
 func (v *View) Size() (x, y int) {
-	return v.x1 - v.x0 - 1, v.y1 - v.y0 - 1
+	_, _, width, height := v.contentArea()
+	return width - v.gutterWidth(), height
+}
+
+// scrollbarThumb returns the first row and the number of rows of the thumb
+// of the scrollbar, relative to the top of the content area, for content of
+// total view lines. ok is false if the whole content fits in the view.
+func (v *View) scrollbarThumb(total int) (start, size int, ok bool) {
+	_, height := v.Size()
+	if v.oy+height > total {
+		total = v.oy + height
+	}
+	if height <= 0 || total <= height {
+		return 0, 0, false
+	}
+
+	size = height * height / total
+	if size < 1 {
+		size = 1
+	}
+	start = v.oy * (height - size) / (total - height)
+	if v.oy > 0 && start == 0 && height > size {
+		// Only the top of the content shows the thumb at the top
+		start = 1
+	}
+	return start, size, true
+}
+
+// contentArea returns the screen position of the top left cell of the
+// content area of the view, where the gutter starts, and its size. The
+// area is inside the sides of the frame that are turned on in FrameSides.
+func (v *View) contentArea() (x, y, width, height int) {
+	x, y = v.x0, v.y0
+	width, height = v.x1-v.x0+1, v.y1-v.y0+1
+	if v.FrameSides.Left {
+		x++
+		width--
+	}
+	if v.FrameSides.Right {
+		width--
+	}
+	if v.FrameSides.Top {
+		y++
+		height--
+	}
+	if v.FrameSides.Bottom {
+		height--
+	}
+	return x, y, width, height
+}
+
+// gutterWidth returns the width of the line numbers gutter, 0 if
+// ShowLineNumbers is false.
+func (v *View) gutterWidth() int {
+	if !v.ShowLineNumbers {
+		return 0
+	}
+	n := len(strconv.Itoa(v.lines.len())) + 1
+	if _, _, w, _ := v.contentArea(); n > w {
+		n = w
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n
+}
+
+// gutterRunes returns the runes of the line numbers gutter drawn before the
+// given view line. Only the first row of a wrapped line is numbered.
+func (v *View) gutterRunes(vline viewLine) []rune {
+	width := v.gutterWidth()
+	runes := []rune(strings.Repeat(" ", width))
+	if vline.x > 0 || width == 0 {
+		return runes
+	}
+	n := vline.y + 1
+	if v.RelativeLineNumbers && vline.y != v.cy {
+		if n = vline.y - v.cy; n < 0 {
+			n = -n
+		}
+	}
+	num := []rune(strconv.Itoa(n))
+	if len(num) > width-1 {
+		num = num[len(num)-(width-1):]
+	}
+	copy(runes[width-1-len(num):], num)
+	return runes
+}
+
+// setGutterRune sets a rune at the given point of the line numbers gutter.
+func (v *View) setGutterRune(x, y int, ch rune, fgColor, bgColor Attribute) error {
+	_, maxY := v.Size()
+	if x < 0 || x >= v.gutterWidth() || y < 0 || y >= maxY {
+		return ErrInvalidPoint
+	}
+	cx, cy, _, _ := v.contentArea()
+	tcellSetCell(cx+x, cy+y, ch, fgColor, bgColor, v.outMode)
+	return nil
 }
 
 // Name returns the name of the view.
@@ -204,7 +620,7 @@ func (v *View) Name() string {
 // setRune sets a rune at the given point relative to the view. It applies the
 // specified colors, taking into account if the cell must be highlighted. Also,
 // it checks if the position is valid.
-func (v *View) setRune(x, y int, ch rune, fgColor, bgColor Attribute) error {
+func (v *View) setRune(x, y int, ch rune, fgColor, bgColor Attribute, combining ...rune) error {
 	maxX, maxY := v.Size()
 	if x < 0 || x >= maxX || y < 0 || y >= maxY {
 		return ErrInvalidPoint
@@ -214,6 +630,7 @@ func (v *View) setRune(x, y int, ch rune, fgColor, bgColor Attribute) error {
 		fgColor = v.FgColor
 		bgColor = v.BgColor
 		ch = v.Mask
+		combining = nil
 	} else if v.Highlight && y == v.cy-v.oy {
 		fgColor = v.SelFgColor | AttrBold
 		bgColor = v.SelBgColor | AttrBold
@@ -223,8 +640,13 @@ func (v *View) setRune(x, y int, ch rune, fgColor, bgColor Attribute) error {
 	if ch == 0 {
 		ch = ' '
 	}
+	// A combining mark without base character is drawn on top of a space
+	if isCombining(ch) {
+		ch, combining = ' ', append([]rune{ch}, combining...)
+	}
 
-	tcellSetCell(v.x0+x+1, v.y0+y+1, ch, fgColor, bgColor, v.outMode)
+	cx, cy, _, _ := v.contentArea()
+	tcellSetCell(cx+x+v.gutterWidth(), cy+y, ch, fgColor, bgColor, v.outMode, combining...)
 
 	return nil
 }
@@ -240,11 +662,29 @@ func (v *View) SetCursorUnrestricted(x, y int) error {
 		return ErrInvalidPoint
 	}
 
-	v.cx = x
-	v.cy = y
+	v.setCursor(x, y)
 	return nil
 }
 
+// setCursor sets the cursor position and calls OnCursorMove if it changed.
+// During an edit, OnCursorMove is called once when the edit ends.
+func (v *View) setCursor(x, y int) {
+	oldX, oldY := v.cx, v.cy
+	v.cx, v.cy = x, y
+	v.hasGoal = false
+	if v.editDepth == 0 {
+		v.cursorMoved(oldX, oldY)
+	}
+}
+
+// cursorMoved calls OnCursorMove if the cursor isn't at (oldX, oldY)
+// anymore.
+func (v *View) cursorMoved(oldX, oldY int) {
+	if v.OnCursorMove != nil && (v.cx != oldX || v.cy != oldY) {
+		v.OnCursorMove(v, oldX, oldY, v.cx, v.cy)
+	}
+}
+
 // SetCursor tries sets the cursor position of the view at the given point
 // If the x or y are outside of the buffer this function will place the cursor on the nearest buffer location
 //
@@ -252,28 +692,131 @@ func (v *View) SetCursorUnrestricted(x, y int) error {
 //   y >= 0
 //   x >= 0
 func (v *View) SetCursor(x, y int) error {
-	if len(v.lines) == 0 {
+	if v.lines.len() == 0 {
 		y = 0
-	} else if y >= len(v.lines) && y != 0 {
-		y = len(v.lines) - 1
+	} else if y >= v.lines.len() && y != 0 {
+		y = v.lines.len() - 1
 	}
 
-	if x > 0 && (len(v.lines) == 0 || len(v.lines[y]) < x) {
-		if len(v.lines) == 0 {
+	if x > 0 && (v.lines.len() == 0 || len(v.lines.line(y)) < x) {
+		if v.lines.len() == 0 {
 			x = 0
 		} else {
-			x = len(v.lines[y])
+			x = len(v.lines.line(y))
 		}
 	}
 
 	return v.SetCursorUnrestricted(x, y)
 }
 
+// SetCursorLogical sets the cursor at the cell col of the line line of the
+// view's internal buffer, the position being clipped to the buffer bounds,
+// and scrolls the view like MoveCursor so the cursor is visible. It returns
+// ErrInvalidPoint if col or line is negative.
+func (v *View) SetCursorLogical(col, line int) error {
+	if col < 0 || line < 0 {
+		return ErrInvalidPoint
+	}
+
+	v.placeCursor(v.clipPoint(col, line))
+	return nil
+}
+
+// SetCursorFromScreen sets the cursor position of the view at the cell of
+// its internal buffer that is displayed at the point (sx, sy) of the screen,
+// e.g. where the mouse was clicked. The view offsets and the wrapping of the
+// lines are taken into account. Points after the end of a line are moved to
+// the end of the line, points below the last line to the last line. It
+// returns ErrInvalidPoint if the point is not inside the view.
+func (v *View) SetCursorFromScreen(sx, sy int) error {
+	x, y, err := v.screenToBuffer(sx, sy)
+	if err != nil {
+		return err
+	}
+	v.setCursor(x, y)
+	return nil
+}
+
+// screenToBuffer returns the position in the view's internal buffer of the
+// cell displayed at the point (sx, sy) of the screen.
+func (v *View) screenToBuffer(sx, sy int) (x, y int, err error) {
+	maxX, maxY := v.Size()
+	gutter := v.gutterWidth()
+	cx, cy, _, _ := v.contentArea()
+	vx, vy := sx-cx-gutter, sy-cy
+	if vx < -gutter || vy < 0 || vx >= maxX || vy >= maxY {
+		return 0, 0, ErrInvalidPoint
+	}
+	if vx < 0 {
+		vx = 0 // The gutter is before the start of the lines
+	}
+	if v.lines.len() == 0 {
+		return 0, 0, nil
+	}
+	if v.VAlign != AlignTop {
+		if vy -= v.valignOffset(v.viewLines()); vy < 0 {
+			vy = 0
+		}
+	}
+
+	if !v.Wrap {
+		y = vy + v.oy
+		if y >= v.lines.len() {
+			y = v.lines.len() - 1
+		}
+		return v.columnCell(v.lines.line(y), vx+v.ox-v.alignOffset(v.lines.line(y))), y, nil
+	}
+
+	lines := v.viewLines()
+	if row := vy + v.oy; row < len(lines) {
+		vx -= v.alignOffset(lines[row].line)
+	}
+	x, y = v.viewLineCell(lines, vy+v.oy, vx)
+	return x, y, nil
+}
+
+// viewLineCell returns the position in the view's internal buffer of the
+// cell displayed at the column col of the given row of lines. The row is
+// clipped to the last one, there must be at least one.
+func (v *View) viewLineCell(lines []viewLine, row, col int) (x, y int) {
+	if row >= len(lines) {
+		row = len(lines) - 1
+	}
+	vline := lines[row]
+	x = v.columnCell(vline.line, col)
+	// The end of a wrapped row is the start of the next one
+	if x == len(vline.line) && x > 0 && row+1 < len(lines) && lines[row+1].y == vline.y {
+		x--
+	}
+	return vline.x + x, vline.y
+}
+
+// columnCell returns the index of the cell of line that is displayed at the
+// column col. It returns the length of the line if col is after its end.
+func (v *View) columnCell(line []cell, col int) int {
+	width := 0
+	for i, c := range line {
+		width += v.cellWidth(c, width)
+		if col < width {
+			return i
+		}
+	}
+	return len(line)
+}
+
 // Cursor returns the cursor position of the view.
 func (v *View) Cursor() (x, y int) {
 	return v.cx, v.cy
 }
 
+// CursorPosition returns the 1-based line and column of the cursor in the
+// view's internal buffer, e.g. for a status line. They don't depend on the
+// view offsets nor on the wrapping of the lines, a tab or a wide character
+// is a single column.
+func (v *View) CursorPosition() (line, col int) {
+	return v.cy + 1, v.cx + 1
+}
+
 // SetOrigin sets the origin position of the view's internal buffer,
 // so the buffer starts to be printed from this point, which means that
 // it is linked with the origin point of view. It can be used to
@@ -293,6 +836,174 @@ func (v *View) Origin() (x, y int) {
 	return v.ox, v.oy
 }
 
+// ViewState is the position of the cursor, the origin and the selection of a
+// view, as saved by SaveState.
+type ViewState struct {
+	cursor    cursorState
+	selection *selection
+}
+
+// SaveState returns the current cursor position, origin and selection of the
+// view, so they can be restored with RestoreState, e.g. after the view was
+// temporarily used to show something else.
+func (v *View) SaveState() ViewState {
+	s := ViewState{cursor: v.cursorState()}
+	if v.selection != nil {
+		sel := *v.selection
+		s.selection = &sel
+	}
+	return s
+}
+
+// RestoreState restores the cursor position, origin and selection saved with
+// SaveState. They are clipped to the current buffer if it has shrunk, the
+// origin being moved so the cursor is visible.
+func (v *View) RestoreState(s ViewState) {
+	_, maxY := v.Size()
+	st := s.cursor
+	if last := len(v.viewLines()) - maxY; st.oy > last {
+		st.oy = last
+	}
+	if st.oy < 0 {
+		st.oy = 0
+	}
+	v.tainted = true
+	v.ox, v.oy = st.ox, st.oy
+	v.placeCursor(v.clipPoint(st.cx, st.cy))
+
+	v.ClearSelection()
+	switch sel := s.selection; {
+	case sel == nil:
+	case sel.block:
+		v.SetBlockSelection(sel.x0, sel.y0, sel.x1, sel.y1)
+	default:
+		v.SetSelection(sel.x0, sel.y0, sel.x1, sel.y1)
+	}
+}
+
+// ScrollPage scrolls the view by delta pages, a page being the height of
+// the view, down if delta is positive and up if it's negative. The view
+// doesn't scroll past the top and the bottom of its content, wrapped lines
+// are counted as several rows. The cursor moves by the same number of rows
+// to stay on screen.
+func (v *View) ScrollPage(delta int) {
+	_, maxY := v.Size()
+	v.scrollRows(delta * maxY)
+}
+
+// ScrollHalfPageDown scrolls the view down by half its height, like ctrl+D
+// in Vim. It works like ScrollPage.
+func (v *View) ScrollHalfPageDown() {
+	_, maxY := v.Size()
+	v.scrollRows((maxY + 1) / 2)
+}
+
+// ScrollHalfPageUp scrolls the view up by half its height, like ctrl+U in
+// Vim. It works like ScrollPage.
+func (v *View) ScrollHalfPageUp() {
+	_, maxY := v.Size()
+	v.scrollRows(-(maxY + 1) / 2)
+}
+
+// ScrollRight scrolls the view n columns to the right, without going past
+// the end of its longest line. ScrollLeft scrolls it n columns to the left,
+// without going past the start of the lines. They do nothing if Wrap is true.
+// The cursor moves horizontally, if needed, to stay on screen as far as its
+// line allows.
+func (v *View) ScrollRight(n int) {
+	v.scrollColumns(n)
+}
+
+// ScrollLeft scrolls the view n columns to the left, see ScrollRight.
+func (v *View) ScrollLeft(n int) {
+	v.scrollColumns(-n)
+}
+
+// scrollColumns scrolls the view horizontally by n columns, see ScrollRight.
+func (v *View) scrollColumns(n int) {
+	maxX, _ := v.Size()
+	if v.Wrap || maxX <= 0 {
+		return
+	}
+
+	ox := v.ox + n
+	if last := v.maxLineWidth() - maxX; ox > last {
+		ox = last
+	}
+	if ox < 0 {
+		ox = 0
+	}
+	v.ox = ox
+
+	if v.cy >= v.lines.len() {
+		return
+	}
+	line := v.lines.line(v.cy)
+	pad := v.alignOffset(line)
+	col := v.lineColumn(v.cx, v.cy) + pad
+	switch {
+	case col < ox:
+		col = ox
+	case col > ox+maxX-1:
+		col = ox + maxX - 1
+	default:
+		return
+	}
+	if col -= pad; col < 0 {
+		col = 0
+	}
+	v.setCursor(v.columnCell(line, col), v.cy)
+}
+
+// CenterCursor scrolls the view so the row of the cursor is in its vertical
+// middle, like zz in Vim. The view doesn't scroll past the top and the
+// bottom of its content.
+func (v *View) CenterCursor() {
+	_, maxY := v.Size()
+	if maxY <= 0 {
+		return
+	}
+
+	_, row, _ := v.linesPosOnScreen(v.cx, v.cy)
+	oy := row - (maxY-1)/2
+	if last := len(v.viewLines()) - maxY; oy > last {
+		oy = last
+	}
+	if oy < 0 {
+		oy = 0
+	}
+	v.oy = oy
+}
+
+// scrollRows moves the origin of the view by n rows and the cursor with it,
+// see ScrollPage.
+func (v *View) scrollRows(n int) {
+	_, maxY := v.Size()
+	if maxY <= 0 || v.lines.len() == 0 {
+		return
+	}
+	lines := v.viewLines()
+
+	oy := v.oy + n
+	if last := len(lines) - maxY; oy > last {
+		oy = last
+	}
+	if oy < 0 {
+		oy = 0
+	}
+
+	col, row, _ := v.linesPosOnScreen(v.cx, v.cy)
+	row += oy - v.oy
+	if row < oy {
+		row = oy
+	}
+	if row > oy+maxY-1 {
+		row = oy + maxY - 1
+	}
+	v.oy = oy
+	v.setCursor(v.viewLineCell(lines, row, col))
+}
+
 // SetWritePos sets the write position of the view's internal buffer.
 // So the next Write call would write directly to the specified position.
 func (v *View) SetWritePos(x, y int) error {
@@ -329,30 +1040,23 @@ func (v *View) ReadPos() (x, y int) {
 // makeWriteable creates empty cells if required to make position (x, y) writeable.
 func (v *View) makeWriteable(x, y int) {
 	// TODO: make this more efficient
+	v.hasMaxWidth = false
 
 	// line `y` must be index-able (that's why `<=`)
-	for len(v.lines) <= y {
-		if cap(v.lines) > len(v.lines) {
-			newLen := cap(v.lines)
-			if newLen > y {
-				newLen = y + 1
-			}
-			v.lines = v.lines[:newLen]
-		} else {
-			v.lines = append(v.lines, nil)
-		}
+	if n := v.lines.len(); n <= y {
+		v.lines.append(make([][]cell, y-n+1)...)
 	}
 	// cell `x` must not be index-able (that's why `<`)
 	// append should be used by `lines[y]` user if he wants to write beyond `x`
-	for len(v.lines[y]) < x {
-		if cap(v.lines[y]) > len(v.lines[y]) {
-			newLen := cap(v.lines[y])
+	for len(v.lines.line(y)) < x {
+		if cap(v.lines.line(y)) > len(v.lines.line(y)) {
+			newLen := cap(v.lines.line(y))
 			if newLen > x {
 				newLen = x
 			}
-			v.lines[y] = v.lines[y][:newLen]
+			v.lines.set(y, v.lines.line(y)[:newLen])
 		} else {
-			v.lines[y] = append(v.lines[y], cell{})
+			v.lines.set(y, append(v.lines.line(y), cell{}))
 		}
 	}
 }
@@ -362,7 +1066,7 @@ func (v *View) makeWriteable(x, y int) {
 func (v *View) writeCells(x, y int, cells []cell) {
 	var newLen int
 	// use maximum len available
-	line := v.lines[y][:cap(v.lines[y])]
+	line := v.lines.line(y)[:cap(v.lines.line(y))]
 	maxCopy := len(line) - x
 	if maxCopy < len(cells) {
 		copy(line[x:], cells[:maxCopy])
@@ -371,48 +1075,214 @@ func (v *View) writeCells(x, y int, cells []cell) {
 	} else { // maxCopy >= len(cells)
 		copy(line[x:], cells)
 		newLen = x + len(cells)
-		if newLen < len(v.lines[y]) {
-			newLen = len(v.lines[y])
+		if newLen < len(v.lines.line(y)) {
+			newLen = len(v.lines.line(y))
 		}
 	}
-	v.lines[y] = line[:newLen]
+	v.lines.set(y, line[:newLen])
 }
 
 // Write appends a byte slice into the view's internal buffer. Because
 // View implements the io.Writer interface, it can be passed as parameter
 // of functions like fmt.Fprintf, fmt.Fprintln, io.Copy, etc. Clear must
-// be called to clear the view's buffer.
+// be called to clear the view's buffer. Escape sequences and UTF-8 encodings
+// can be split across calls, e.g. when copying the output of a command.
 func (v *View) Write(p []byte) (n int, err error) {
-	v.tainted = true
+	defer v.bufferWritten()
 	v.writeMutex.Lock()
 	defer v.writeMutex.Unlock()
+	v.tainted = true
+	v.modified = true
+	v.resetUndo()
 	v.makeWriteable(v.wx, v.wy)
-	v.writeRunes(bytes.Runes(p))
+
+	// Keep an incomplete UTF-8 encoding for the next call, escape sequences
+	// split across calls are handled by the escape interpreter
+	buf := append(v.partialRune, p...)
+	n = len(buf) - incompleteRuneLen(buf)
+	v.partialRune = append([]byte(nil), buf[n:]...)
+	v.writeRunes(bytes.Runes(buf[:n]), AttrNone)
+	v.trimLines()
 
 	return len(p), nil
 }
 
+// incompleteRuneLen returns the length of the incomplete UTF-8 encoding at
+// the end of p, or 0 if p ends with a full encoding.
+func incompleteRuneLen(p []byte) int {
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		if start := len(p) - i; utf8.RuneStart(p[start]) {
+			if utf8.FullRune(p[start:]) {
+				return 0
+			}
+			return i
+		}
+	}
+	return 0
+}
+
+// WriteRunes writes the given runes like Write.
 func (v *View) WriteRunes(p []rune) {
+	defer v.bufferWritten()
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
 	v.tainted = true
+	v.modified = true
+	v.resetUndo()
 
 	// Fill with empty cells, if writing outside current view buffer
 	v.makeWriteable(v.wx, v.wy)
-	v.writeRunes(p)
+	v.writeRunes(p, AttrNone)
+	v.trimLines()
 }
 
+// WriteString writes the given string like Write.
 func (v *View) WriteString(s string) {
 	v.WriteRunes([]rune(s))
 }
 
-// writeRunes copies slice of runes into internal lines buffer.
+// WriteStyled writes a string like Write, using the text attributes attr
+// (e.g. AttrBold|AttrUnderline) in addition to the colors and attributes
+// set by escape sequences. The color bits of attr are ignored.
+func (v *View) WriteStyled(s string, attr Attribute) {
+	defer v.bufferWritten()
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	v.tainted = true
+	v.modified = true
+	v.resetUndo()
+	v.makeWriteable(v.wx, v.wy)
+	v.writeRunes([]rune(s), attr&AttrStyleBits)
+	v.trimLines()
+}
+
+// WriteColored writes a string like Write, using the colors fg and bg
+// instead of the current ones, e.g. to build a status line out of colored
+// parts. The colors set by escape sequences before the call are restored
+// afterwards.
+func (v *View) WriteColored(fg, bg Attribute, s string) {
+	defer v.bufferWritten()
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	v.tainted = true
+	v.modified = true
+	v.resetUndo()
+	v.makeWriteable(v.wx, v.wy)
+
+	curFgColor, curBgColor := v.ei.curFgColor, v.ei.curBgColor
+	v.ei.curFgColor, v.ei.curBgColor = fg, bg
+	v.writeRunes([]rune(s), 0)
+	v.ei.curFgColor, v.ei.curBgColor = curFgColor, curBgColor
+	v.trimLines()
+}
+
+// AppendLine adds the given text at the end of the view's internal buffer,
+// as new lines. Escape sequences are interpreted like in Write. Contrary to
+// Write, the write position, the cursor and the origin are not changed, so
+// reading back the content is not disturbed, unless Autoscroll is true: the
+// origin is then moved to show the new lines.
+func (v *View) AppendLine(s string) {
+	defer v.bufferWritten()
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	v.tainted = true
+	v.modified = true
+	v.hasMaxWidth = false
+	v.resetUndo()
+
+	for _, text := range strings.Split(s, "\n") {
+		line := []cell{}
+		for _, r := range text {
+			cells := v.parseInput(r)
+			if n := len(line); n > 0 && len(cells) == 1 && isCombining(cells[0].chr) {
+				line[n-1].combining = append(line[n-1].combining, cells[0].chr)
+				continue
+			}
+			line = append(line, cells...)
+		}
+		v.lines.append(line)
+	}
+	v.trimLines()
+
+	if v.Autoscroll {
+		_, maxY := v.Size()
+		v.oy = autoscrollOrigin(v.viewLines(), maxY)
+	}
+}
+
+// trimLines drops the oldest lines of the view's internal buffer if it holds
+// more than MaxLines lines, and moves the positions that refer to the buffer
+// so they stay on the same text. Positions inside the dropped lines are moved
+// to the start of the buffer.
+func (v *View) trimLines() {
+	if v.MaxLines <= 0 {
+		return
+	}
+	n := v.lines.len() - v.MaxLines
+	if last := v.lines.len() - 1; last >= 0 && len(v.lines.line(last)) == 0 {
+		n--
+	}
+	if n <= 0 {
+		return
+	}
+
+	rows := n
+	if v.Wrap {
+		rows = 0
+		for y := 0; y < n; y++ {
+			line := v.lines.line(y)
+			for end := false; !end; rows++ {
+				_, _, end = v.takeLine(&line)
+			}
+		}
+	}
+	v.lines.remove(0, n)
+
+	shift := func(x, y *int) {
+		if *y -= n; *y < 0 {
+			*x, *y = 0, 0
+		}
+	}
+	shift(&v.wx, &v.wy)
+	shift(&v.rx, &v.ry)
+	shift(&v.cx, &v.cy)
+	shift(&v.dragX, &v.dragY)
+	if k := v.keptCursor; k != nil {
+		shift(&k.x, &k.y)
+		shift(&k.cx, &k.cy)
+	}
+	switch s := v.selection; {
+	case s == nil:
+	case s.block:
+		// The columns of a block don't depend on the lines
+		if s.y0, s.y1 = s.y0-n, s.y1-n; s.y1 < 0 {
+			v.selection = nil
+		} else if s.y0 < 0 {
+			s.y0 = 0
+		}
+	default:
+		shift(&s.x0, &s.y0)
+		shift(&s.x1, &s.y1)
+		if s.x0 == s.x1 && s.y0 == s.y1 {
+			v.selection = nil
+		}
+	}
+	if v.oy -= rows; v.oy < 0 {
+		v.oy = 0
+	}
+}
+
+// writeRunes copies slice of runes into internal lines buffer, adding the
+// text attributes attr to the cells.
 // caller must make sure that writing position is accessable.
-func (v *View) writeRunes(p []rune) {
+func (v *View) writeRunes(p []rune, attr Attribute) {
+	v.hasMaxWidth = false
 	for _, r := range p {
 		switch r {
 		case '\n':
 			v.wy++
-			if v.wy >= len(v.lines) {
-				v.lines = append(v.lines, nil)
+			if v.wy >= v.lines.len() {
+				v.lines.append(nil)
 			}
 
 			fallthrough
@@ -424,6 +1294,12 @@ func (v *View) writeRunes(p []rune) {
 			if cells == nil {
 				continue
 			}
+			if len(cells) == 1 && v.combineRune(v.wx, v.wy, cells[0].chr) {
+				continue
+			}
+			for i := range cells {
+				cells[i].fgColor |= attr
+			}
 			v.writeCells(v.wx, v.wy, cells)
 			v.wx += len(cells)
 		}
@@ -434,39 +1310,22 @@ func (v *View) writeRunes(p []rune) {
 // while processing ESC sequences. Otherwise, it returns a cell slice that
 // contains the processed data.
 func (v *View) parseInput(ch rune) []cell {
-	cells := []cell{}
-
 	isEscape, err := v.ei.parseOne(ch)
 	if err != nil {
-		for _, r := range v.ei.runes() {
-			c := cell{
-				fgColor: v.FgColor,
-				bgColor: v.BgColor,
-				chr:     r,
-			}
-			cells = append(cells, c)
-		}
-		v.ei.reset()
-	} else {
-		if isEscape {
-			return nil
-		}
-		repeatCount := 1
-		if ch == '\t' {
-			ch = ' '
-			repeatCount = 4
-		}
-		for i := 0; i < repeatCount; i++ {
-			c := cell{
-				fgColor: v.ei.curFgColor,
-				bgColor: v.ei.curBgColor,
-				chr:     ch,
-			}
-			cells = append(cells, c)
-		}
+		// Drop the escape sequences that can't be parsed
+		v.ei.abort()
+		return nil
+	}
+	if isEscape {
+		return nil
 	}
 
-	return cells
+	c := cell{
+		fgColor: v.ei.curFgColor,
+		bgColor: v.ei.curBgColor,
+		chr:     ch,
+	}
+	return []cell{c}
 }
 
 // Read reads data into p from the current reading position set by SetReadPos.
@@ -485,9 +1344,9 @@ func (v *View) Read(p []byte) (n int, err error) {
 		}
 		v.readBuffer = nil
 	}
-	for v.ry < len(v.lines) {
-		for v.rx < len(v.lines[v.ry]) {
-			count := utf8.EncodeRune(buffer, v.lines[v.ry][v.rx].chr)
+	for v.ry < v.lines.len() {
+		for v.rx < len(v.lines.line(v.ry)) {
+			count := utf8.EncodeRune(buffer, v.lines.line(v.ry)[v.rx].chr)
 			copy(p[offset:], buffer[:count])
 			v.rx++
 			newOffset := offset + count
@@ -519,17 +1378,30 @@ func (v *View) Rewind() {
 	}
 }
 
+// viewLine is a line as it's rendered on the screen. y is the index of the
+// buffer line it belongs to and x the index of its first cell in that line.
+type viewLine struct {
+	line []cell
+	x, y int
+}
+
 // viewLines returns the lines to render on the screen
-func (v *View) viewLines() [][]cell {
+func (v *View) viewLines() []viewLine {
+	renderLines := make([]viewLine, 0, v.lines.len())
 	if !v.Wrap {
-		return v.lines
+		for y := 0; y < v.lines.len(); y++ {
+			renderLines = append(renderLines, viewLine{line: v.lines.line(y), y: y})
+		}
+		return renderLines
 	}
 
-	renderLines := [][]cell{}
-	for _, viewLine := range v.lines {
+	for y := 0; y < v.lines.len(); y++ {
+		line := v.lines.line(y)
+		x := 0
 		for {
-			lineToRender, _, end := v.takeLine(&viewLine)
-			renderLines = append(renderLines, lineToRender)
+			lineToRender, _, end := v.takeLine(&line)
+			renderLines = append(renderLines, viewLine{line: lineToRender, x: x, y: y})
+			x += len(lineToRender)
 			if end {
 				break
 			}
@@ -538,6 +1410,30 @@ func (v *View) viewLines() [][]cell {
 	return renderLines
 }
 
+// autoscrollOrigin returns the y-origin that shows the last of the given view
+// lines at the bottom of a view of height maxY. An empty last line, left by a
+// trailing newline, is not shown.
+func autoscrollOrigin(lines []viewLine, maxY int) int {
+	n := len(lines)
+	if n > 0 && len(lines[n-1].line) == 0 {
+		n--
+	}
+	if n <= maxY {
+		return 0
+	}
+	return n - maxY
+}
+
+// trailingWhitespace returns the index of the first cell of the whitespace
+// that ends line, the length of the line if it doesn't end with whitespace.
+func trailingWhitespace(line []cell) int {
+	n := len(line)
+	for n > 0 && (line[n-1].chr == ' ' || line[n-1].chr == '\t') {
+		n--
+	}
+	return n
+}
+
 // IsTainted tells us if the view is tainted
 func (v *View) IsTainted() bool {
 	return v.tainted
@@ -549,35 +1445,59 @@ func (v *View) draw() error {
 		return nil
 	}
 
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+
 	maxX, maxY := v.Size()
 
 	if v.Wrap {
 		if maxX == 0 {
 			// Just return here, there is no need to try drawing chars in a too small frame
 			// Nor is it needed to return an error, there is just no space
+			v.thumbSize = 0
 			return nil
 		}
 		v.ox = 0
 	}
 
-	if !v.tainted && v.contentCache != nil {
+	if !v.tainted && v.contentCache != nil && v.drawnState == v.renderState() {
 		for _, cell := range v.contentCache {
-			if err := v.setRune(cell.x, cell.y, cell.chr, cell.fgColor, cell.bgColor); err != nil {
+			var err error
+			if cell.gutter {
+				err = v.setGutterRune(cell.x, cell.y, cell.chr, cell.fgColor, cell.bgColor)
+			} else {
+				err = v.setRune(cell.x, cell.y, cell.chr, cell.fgColor, cell.bgColor, cell.combining...)
+			}
+			if err != nil {
 				return err
 			}
 		}
 		return nil
 	}
 
+	v.updateSearchMatches()
+	bracketX, bracketY, bracket := 0, 0, false
+	if v.HighlightMatchingBracket {
+		bracketX, bracketY, bracket = v.MatchingBracket()
+	}
 	linesToRender := v.viewLines()
+	total := len(linesToRender)
+	placeholder := v.Placeholder != "" && v.isEmpty()
+	numbered, whitespace := v.ShowLineNumbers, v.ShowWhitespace
+	if placeholder {
+		linesToRender = v.placeholderLines()
+		numbered, whitespace = false, false
+	}
 
-	if v.Autoscroll && len(linesToRender) > maxY {
-		v.oy = len(linesToRender) - maxY - 1
+	if v.Autoscroll {
+		v.oy = autoscrollOrigin(linesToRender, maxY)
 	}
+	// The scrollbar is drawn by the Gui, without holding the lock
+	v.thumbStart, v.thumbSize, _ = v.scrollbarThumb(total)
 
-	newCache := []cellCache{}
-	y := 0
-	for lineIndex, line := range linesToRender {
+	newCache := make([]cellCache, 0, len(v.contentCache))
+	y := v.valignOffset(linesToRender)
+	for lineIndex, vline := range linesToRender {
 		if lineIndex < v.oy {
 			continue
 		}
@@ -585,60 +1505,365 @@ func (v *View) draw() error {
 			break // No need to render out of screen chars
 		}
 
-		x := 0
-		for charIndex, char := range line {
-			if charIndex < v.ox {
+		if numbered {
+			for i, r := range v.gutterRunes(vline) {
+				newCache = append(newCache, cellCache{
+					chr:     r,
+					fgColor: v.LineNumberFgColor,
+					bgColor: v.BgColor,
+					x:       i,
+					y:       y,
+					gutter:  true,
+				})
+				if err := v.setGutterRune(i, y, r, v.LineNumberFgColor, v.BgColor); err != nil {
+					return err
+				}
+			}
+		}
+
+		// The background of the current line fills the whole row
+		current := v.HighlightCurrentLine && !placeholder && vline.y == v.cy
+		if current {
+			for x := 0; x < maxX; x++ {
+				newCache = append(newCache, cellCache{
+					chr:     ' ',
+					bgColor: v.CurrentLineBgColor,
+					fgColor: v.FgColor,
+					x:       x,
+					y:       y,
+				})
+				if err := v.setRune(x, y, ' ', v.FgColor, v.CurrentLineBgColor); err != nil {
+					return err
+				}
+			}
+		}
+
+		trailing := 0
+		if whitespace {
+			trailing = trailingWhitespace(v.lines.line(vline.y))
+		}
+
+		// lastX is the column of the cell drawn in the last column of the
+		// view, replaced by the ellipsis if the line overflows
+		ellipsis := v.EllipsisOverflow && !v.Wrap && !v.Editable && !placeholder
+		lastX, lastFg, lastBg := maxX, Attribute(0), Attribute(0)
+
+		col, pad := 0, v.alignOffset(vline.line)
+		for charIndex, char := range vline.line {
+			width := v.cellWidth(char, col)
+			x := col - v.ox + pad
+			col += width
+			if x+width <= 0 {
 				continue
 			}
 			if x >= maxX {
 				break // No need to render out of screen chars
 			}
 
+			// The text attributes of a cell are kept with the view colors
 			fgColor := char.fgColor
-			if fgColor == ColorDefault {
-				fgColor = v.FgColor
+			if fgColor&AttrColorBits == ColorDefault {
+				fgColor |= v.FgColor
 			}
 			bgColor := char.bgColor
-			if bgColor == ColorDefault {
-				bgColor = v.BgColor
+			if bgColor&AttrColorBits == ColorDefault {
+				if current {
+					bgColor |= v.CurrentLineBgColor
+				} else {
+					bgColor |= v.BgColor
+				}
+			}
+			isSpace := char.chr == ' ' || char.chr == '\t'
+			if whitespace && isSpace {
+				fgColor = v.WhitespaceFgColor
+				if vline.x+charIndex >= trailing && v.TrailingWhitespaceBgColor != ColorDefault {
+					bgColor = v.TrailingWhitespaceBgColor
+				}
+			}
+			if v.isSearchMatch(vline.x+charIndex, vline.y) {
+				fgColor, bgColor = v.SearchFgColor, v.SearchBgColor
+			}
+			if bracket && !placeholder {
+				if bx, by := vline.x+charIndex, vline.y; (bx == bracketX && by == bracketY) || (bx == v.cx && by == v.cy) {
+					fgColor, bgColor = v.MatchingBracketFgColor, v.MatchingBracketBgColor
+				}
+			}
+			if v.isSelected(vline.x+charIndex, vline.y) {
+				fgColor, bgColor = v.SelFgColor, v.SelBgColor
 			}
 
-			newCache = append(newCache, cellCache{
-				chr:     char.chr,
-				bgColor: bgColor,
-				fgColor: fgColor,
-				x:       x,
-				y:       y,
-			})
-			if err := v.setRune(x, y, char.chr, fgColor, bgColor); err != nil {
-				return err
+			// A tab is drawn as spaces up to the next tab stop, the visible
+			// part of a wide character cut by the view edges too
+			chr, combining, n := char.chr, char.combining, 1
+			if chr == '\t' || (width > 1 && (x < 0 || x+width > maxX)) {
+				chr, combining, n = ' ', nil, width
 			}
-			if char.chr == 0 {
-				x++ // if NULL increase, so `SetWritePos` can be used (NULL translate to SPACE in setRune)
-			} else {
-				x += runewidth.RuneWidth(char.chr)
+			if x+width >= maxX {
+				lastX, lastFg, lastBg = x, fgColor, bgColor
+			}
+			first := chr
+			if whitespace && isSpace {
+				first = '·'
+				if char.chr == '\t' {
+					first = '→'
+				}
+			}
+			for i := 0; i < n && x+i < maxX; i++ {
+				if x+i < 0 {
+					continue
+				}
+				chr := chr
+				if i == 0 {
+					chr = first
+				}
+				newCache = append(newCache, cellCache{
+					chr:       chr,
+					combining: combining,
+					bgColor:   bgColor,
+					fgColor:   fgColor,
+					x:         x + i,
+					y:         y,
+				})
+				if err := v.setRune(x+i, y, chr, fgColor, bgColor, combining...); err != nil {
+					return err
+				}
+			}
+		}
+		if ellipsis && lastX < maxX && v.lineWidth(vline.line)+pad-v.ox > maxX {
+			if lastX < 0 {
+				lastX = 0
+			}
+			for x := lastX; x < maxX; x++ {
+				chr := ' '
+				if x == maxX-1 {
+					chr = '…'
+				}
+				newCache = append(newCache, cellCache{
+					chr:     chr,
+					bgColor: lastBg,
+					fgColor: lastFg,
+					x:       x,
+					y:       y,
+				})
+				if err := v.setRune(x, y, chr, lastFg, lastBg); err != nil {
+					return err
+				}
 			}
 		}
 		y++
 	}
 
 	v.contentCache = newCache
+	v.drawnState = v.renderState()
+	v.tainted = false
 	return nil
 }
 
-// Clear empties the view and resets the view offsets, cursor position, read offsets and write offsets
+// renderState holds the settings of a view its content is drawn with,
+// besides the buffer itself.
+type renderState struct {
+	x0, y0, x1, y1, ox, oy       int
+	wrap, wrapWords, autoscroll  bool
+	ellipsisOverflow             bool
+	editable, highlight          bool
+	tabWidth                     int
+	mask                         rune
+	fgColor, bgColor             Attribute
+	selFgColor, selBgColor       Attribute
+	searchFgColor, searchBgColor Attribute
+	alignment                    Alignment
+	valign                       VerticalAlignment
+	placeholder                  string
+	placeholderFgColor           Attribute
+	showLineNumbers              bool
+	lineNumberFgColor            Attribute
+	relativeLineNumbers          bool
+	showWhitespace               bool
+	whitespaceFgColor            Attribute
+	trailingWhitespaceBgColor    Attribute
+	highlightCurrentLine         bool
+	currentLineBgColor           Attribute
+	frameSides                   FrameSides
+	highlightMatchingBracket     bool
+	matchingBracketFgColor       Attribute
+	matchingBracketBgColor       Attribute
+
+	// cursorX and cursorLine are the position of the cursor if the content
+	// depends on it, e.g. with Highlight, 0 otherwise
+	cursorX, cursorLine int
+}
+
+// renderState returns the current settings of the view that change how its
+// content is drawn.
+func (v *View) renderState() renderState {
+	s := renderState{
+		x0: v.x0, y0: v.y0, x1: v.x1, y1: v.y1,
+		ox: v.ox, oy: v.oy,
+		wrap:                      v.Wrap,
+		wrapWords:                 v.WrapWords,
+		ellipsisOverflow:          v.EllipsisOverflow,
+		autoscroll:                v.Autoscroll,
+		editable:                  v.Editable,
+		highlight:                 v.Highlight,
+		tabWidth:                  v.TabWidth,
+		mask:                      v.Mask,
+		fgColor:                   v.FgColor,
+		bgColor:                   v.BgColor,
+		selFgColor:                v.SelFgColor,
+		selBgColor:                v.SelBgColor,
+		searchFgColor:             v.SearchFgColor,
+		searchBgColor:             v.SearchBgColor,
+		alignment:                 v.Alignment,
+		valign:                    v.VAlign,
+		placeholder:               v.Placeholder,
+		placeholderFgColor:        v.PlaceholderFgColor,
+		showLineNumbers:           v.ShowLineNumbers,
+		lineNumberFgColor:         v.LineNumberFgColor,
+		relativeLineNumbers:       v.RelativeLineNumbers,
+		showWhitespace:            v.ShowWhitespace,
+		whitespaceFgColor:         v.WhitespaceFgColor,
+		trailingWhitespaceBgColor: v.TrailingWhitespaceBgColor,
+		highlightCurrentLine:      v.HighlightCurrentLine,
+		currentLineBgColor:        v.CurrentLineBgColor,
+		frameSides:                v.FrameSides,
+		highlightMatchingBracket:  v.HighlightMatchingBracket,
+		matchingBracketFgColor:    v.MatchingBracketFgColor,
+		matchingBracketBgColor:    v.MatchingBracketBgColor,
+	}
+	if v.Highlight || v.HighlightCurrentLine || v.HighlightMatchingBracket || (v.ShowLineNumbers && v.RelativeLineNumbers) {
+		s.cursorLine = v.cy
+	}
+	if v.HighlightMatchingBracket {
+		s.cursorX = v.cx
+	}
+	return s
+}
+
+// alignOffset returns the number of columns drawn before the line to align
+// it according to Alignment.
+func (v *View) alignOffset(line []cell) int {
+	if v.Editable || v.Alignment == AlignLeft {
+		return 0
+	}
+	maxX, _ := v.Size()
+	width := v.lineWidth(line)
+	if width >= maxX {
+		return 0
+	}
+	if v.Alignment == AlignCenter {
+		return (maxX - width) / 2
+	}
+	return maxX - width
+}
+
+// valignOffset returns the number of rows drawn before the given lines to
+// align them according to VAlign. An empty last line, left by a trailing
+// newline, is not taken into account.
+func (v *View) valignOffset(lines []viewLine) int {
+	if v.Editable || v.VAlign == AlignTop || v.oy > 0 {
+		return 0
+	}
+	_, maxY := v.Size()
+	n := len(lines)
+	if n > 0 && len(lines[n-1].line) == 0 {
+		n--
+	}
+	if n >= maxY {
+		return 0
+	}
+	if v.VAlign == AlignMiddle {
+		return (maxY - n) / 2
+	}
+	return maxY - n
+}
+
+// isEmpty reports whether the view's internal buffer has no content.
+func (v *View) isEmpty() bool {
+	return v.lines.len() == 0 || (v.lines.len() == 1 && len(v.lines.line(0)) == 0)
+}
+
+// placeholderLines returns the lines of the placeholder to draw them like
+// the view's content.
+func (v *View) placeholderLines() []viewLine {
+	lines := []viewLine{}
+	for _, s := range strings.Split(v.Placeholder, "\n") {
+		line := []cell{}
+		for _, r := range s {
+			line = append(line, cell{chr: r, fgColor: v.PlaceholderFgColor, bgColor: ColorDefault})
+		}
+		lines = append(lines, viewLine{line: line, y: len(lines)})
+	}
+	return lines
+}
+
+// Clear empties the view and resets the view offsets, cursor position, read offsets and write offsets.
+// The colors set by escape sequences are reset too, FgColor and BgColor
+// aren't changed.
 func (v *View) Clear() {
+	defer v.bufferWritten()
 	v.writeMutex.Lock()
 	defer v.writeMutex.Unlock()
+	v.clear()
+}
+
+// clear empties the view like Clear, the buffer must be locked.
+func (v *View) clear() {
 	v.Rewind()
 	v.tainted = true
+	v.modified = true
+	v.hasMaxWidth = false
 	v.ei.reset()
-	v.lines = [][]cell{}
+	v.partialRune = nil
+	v.resetUndo()
+	v.keptCursor = nil
+	v.lines = lineBuffer{}
 	v.SetCursor(0, 0)
 	v.SetOrigin(0, 0)
 	v.clearRunes()
 }
 
+// keptCursor is a cursor position saved by ClearKeepCursor. x, y is the
+// saved position and cx, cy where the cursor was put after the last write.
+type keptCursor struct {
+	x, y, cx, cy int
+}
+
+// ClearKeepCursor empties the view like Clear, but the cursor gets back to
+// its position as the content is written again, e.g. when refreshing a list
+// the user is navigating: after each write, the cursor is moved to that
+// position clipped to the buffer, until it's moved by something else. The
+// cursor is at (0, 0) while the buffer is empty. The origin is reset to
+// (0, 0) and scrolled like with MoveCursor as the cursor gets back, so the
+// cursor stays visible.
+func (v *View) ClearKeepCursor() {
+	defer v.bufferWritten()
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	x, y := v.cx, v.cy
+	v.clear()
+	v.keptCursor = &keptCursor{x: x, y: y, cx: v.cx, cy: v.cy}
+}
+
+// restoreCursor moves the cursor back to the position saved by
+// ClearKeepCursor, unless it was moved since the last write.
+func (v *View) restoreCursor() {
+	v.writeMutex.Lock()
+	oldX, oldY := v.cx, v.cy
+	if k := v.keptCursor; k != nil {
+		if v.cx != k.cx || v.cy != k.cy {
+			v.keptCursor = nil
+		} else {
+			// OnCursorMove is called once the buffer is unlocked
+			v.editDepth++
+			v.placeCursor(v.clipPoint(k.x, k.y))
+			v.editDepth--
+			k.cx, k.cy = v.cx, v.cy
+		}
+	}
+	v.writeMutex.Unlock()
+	v.cursorMoved(oldX, oldY)
+}
+
 // linesPosOnScreen returns based on the view lines the x and y location
 // the viewX and viewY are NOT based on the view offsets
 // isOnScreen is false if the selected corodinates is not on screen (this is based on the view offsets)
@@ -649,7 +1874,7 @@ func (v *View) linesPosOnScreen(x, y int) (viewX int, viewY int, visable bool) {
 
 	maxX, maxY := v.Size()
 	if !v.Wrap {
-		viewX = x
+		viewX = v.lineColumn(x, y)
 		viewY = y
 		visable = viewY >= v.oy && viewY < v.oy+maxY && viewX >= v.ox && viewX < v.ox+maxX
 		return
@@ -658,7 +1883,8 @@ func (v *View) linesPosOnScreen(x, y int) (viewX int, viewY int, visable bool) {
 	var line []cell
 	found := false
 
-	for lineIndex, viewLine := range v.lines {
+	for lineIndex := 0; lineIndex < v.lines.len(); lineIndex++ {
+		viewLine := v.lines.line(lineIndex)
 		if lineIndex == y {
 			line = viewLine
 			found = true
@@ -679,7 +1905,7 @@ func (v *View) linesPosOnScreen(x, y int) (viewX int, viewY int, visable bool) {
 			lineChars, width, end := v.takeLine(&line)
 			lenLineChars := len(lineChars)
 			if x < lenLineChars {
-				x = lineWidth(lineChars[:x])
+				x = v.lineWidth(lineChars[:x])
 				break
 			} else {
 				x -= lenLineChars
@@ -692,10 +1918,10 @@ func (v *View) linesPosOnScreen(x, y int) (viewX int, viewY int, visable bool) {
 			viewY++
 		}
 	} else {
-		if y < len(v.lines) {
+		if y < v.lines.len() {
 			viewY = y
 		} else {
-			viewY += y - len(v.lines)
+			viewY += y - v.lines.len()
 		}
 	}
 
@@ -708,10 +1934,10 @@ func (v *View) linesPosOnScreen(x, y int) (viewX int, viewY int, visable bool) {
 
 // clearRunes erases all the cells in the view.
 func (v *View) clearRunes() {
-	maxX, maxY := v.Size()
+	cx, cy, maxX, maxY := v.contentArea()
 	for x := 0; x < maxX; x++ {
 		for y := 0; y < maxY; y++ {
-			tcellSetCell(v.x0+x+1, v.y0+y+1, ' ', v.FgColor, v.BgColor, v.outMode)
+			tcellSetCell(cx+x, cy+y, ' ', v.FgColor, v.BgColor, v.outMode)
 		}
 	}
 }
@@ -719,28 +1945,195 @@ func (v *View) clearRunes() {
 // BufferLines returns the lines in the view's internal
 // buffer.
 func (v *View) BufferLines() []string {
-	lines := make([]string, len(v.lines))
-	for i, l := range v.lines {
-		str := lineType(l).String()
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	lines := make([]string, v.lines.len())
+	for i := range lines {
+		str := lineType(v.lines.line(i)).String()
 		str = strings.Replace(str, "\x00", " ", -1)
 		lines[i] = str
 	}
 	return lines
 }
 
+// Lines returns a copy of the logical lines in the view's internal buffer.
+// Contrary to BufferLines, the empty cells that pad the end of a line are
+// not included.
+func (v *View) Lines() []string {
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	lines := make([]string, v.lines.len())
+	for i := range lines {
+		l := v.lines.line(i)
+		end := len(l)
+		for end > 0 && l[end-1].chr == 0 {
+			end--
+		}
+		str := lineType(l[:end]).String()
+		lines[i] = strings.Replace(str, "\x00", " ", -1)
+	}
+	return lines
+}
+
+// Reader returns a reader of the logical lines of the view's internal buffer,
+// as returned by Lines, joined with '\n'. It reads a copy of the content made
+// when Reader is called, so later changes of the view don't affect it.
+func (v *View) Reader() io.Reader {
+	return strings.NewReader(strings.Join(v.Lines(), "\n"))
+}
+
+// SetContent replaces the content of the view's internal buffer with the
+// given lines, using the view's colors. The runes are stored as is, escape
+// sequences are not interpreted. The cursor is kept if it's still inside the
+// buffer, otherwise it's moved to the nearest position.
+func (v *View) SetContent(lines []string) {
+	defer v.bufferWritten()
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+
+	v.tainted = true
+	v.modified = true
+	v.hasMaxWidth = false
+	v.resetUndo()
+	v.lines = newLineBuffer(make([][]cell, len(lines)))
+	for i, l := range lines {
+		line := make([]cell, 0, len(l))
+		for _, r := range l {
+			if n := len(line); n > 0 && isCombining(r) {
+				line[n-1].combining = append(line[n-1].combining, r)
+				continue
+			}
+			line = append(line, cell{
+				fgColor: v.FgColor,
+				bgColor: v.BgColor,
+				chr:     r,
+			})
+		}
+		v.lines.set(i, line)
+	}
+
+	v.readBuffer = nil
+	v.rx, v.ry = 0, 0
+	v.wx, v.wy = 0, 0
+	if n := v.lines.len(); n > 0 {
+		v.wx, v.wy = len(v.lines.line(n-1)), n-1
+	}
+
+	v.ox, v.oy = 0, 0
+	if v.lines.len() == 0 {
+		v.setCursor(0, 0)
+		return
+	}
+	v.placeCursor(v.clipPoint(v.cx, v.cy))
+}
+
+// Cell returns the rune and the colors of the cell (x, y) of the view's
+// internal buffer. The cells added before a point written beyond the end of
+// a line hold the rune 0. ok is false if there is no such cell.
+func (v *View) Cell(x, y int) (ch rune, fg, bg Attribute, ok bool) {
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	if x < 0 || y < 0 || y >= v.lines.len() || x >= len(v.lines.line(y)) {
+		return 0, 0, 0, false
+	}
+	c := v.lines.line(y)[x]
+	return c.chr, c.fgColor, c.bgColor, true
+}
+
+// SetCell replaces the cell (x, y) of the view's internal buffer by one
+// holding ch with the given colors, e.g. to draw a chart or a game board
+// without going through the editor. Escape sequences are not interpreted.
+// The buffer is extended if the point is beyond its end, like Write does.
+// It returns ErrInvalidPoint if x or y is negative.
+func (v *View) SetCell(x, y int, ch rune, fg, bg Attribute) error {
+	if x < 0 || y < 0 {
+		return ErrInvalidPoint
+	}
+
+	defer v.bufferWritten()
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	v.tainted = true
+	v.modified = true
+	v.resetUndo()
+	v.setCell(x, y, cell{chr: ch, fgColor: fg, bgColor: bg})
+	return nil
+}
+
+// FillRect sets the cells of the rectangle of w columns and h rows whose top
+// left corner is the cell (x, y) of the view's internal buffer like SetCell,
+// e.g. to draw a background or a progress bar. The buffer is extended if the
+// rectangle is beyond its end. The part of the rectangle at negative
+// coordinates is ignored.
+func (v *View) FillRect(x, y, w, h int, ch rune, fg, bg Attribute) {
+	if x < 0 {
+		w, x = w+x, 0
+	}
+	if y < 0 {
+		h, y = h+y, 0
+	}
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	defer v.bufferWritten()
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	v.tainted = true
+	v.modified = true
+	v.resetUndo()
+	c := cell{chr: ch, fgColor: fg, bgColor: bg}
+	for cy := y; cy < y+h; cy++ {
+		for cx := x; cx < x+w; cx++ {
+			v.setCell(cx, cy, c)
+		}
+	}
+}
+
+// DrawHLine sets length cells of the line y of the view's internal buffer
+// to ch, starting at the cell x, using the view's colors. It's FillRect for
+// a rectangle one row high, e.g. to draw a separator.
+func (v *View) DrawHLine(x, y, length int, ch rune) {
+	v.FillRect(x, y, length, 1, ch, v.FgColor, v.BgColor)
+}
+
+// DrawVLine sets the cell x of length lines of the view's internal buffer to
+// ch, starting at the line y, using the view's colors. It's FillRect for a
+// rectangle one column wide.
+func (v *View) DrawVLine(x, y, length int, ch rune) {
+	v.FillRect(x, y, 1, length, ch, v.FgColor, v.BgColor)
+}
+
+// setCell replaces the cell (x, y) of the view's internal buffer by c,
+// adding empty lines and cells if the point is beyond the end of the buffer.
+func (v *View) setCell(x, y int, c cell) {
+	v.hasMaxWidth = false
+	if y >= v.lines.len() {
+		v.lines.append(make([][]cell, y-v.lines.len()+1)...)
+	}
+	if n := len(v.lines.line(y)); x >= n {
+		v.lines.set(y, append(v.lines.line(y), make([]cell, x-n+1)...))
+	}
+	v.lines.line(y)[x] = c
+}
+
 // Buffer returns a string with the contents of the view's internal
 // buffer.
 func (v *View) Buffer() string {
-	return linesToString(v.lines)
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	return linesToString(v.lines.slice(0, v.lines.len()))
 }
 
 // ViewBufferLines returns the lines in the view's internal
 // buffer that is shown to the user.
 func (v *View) ViewBufferLines() []string {
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
 	viewLines := v.viewLines()
 	lines := make([]string, len(viewLines))
-	for i, line := range viewLines {
-		str := lineType(line).String()
+	for i, vline := range viewLines {
+		str := lineType(vline.line).String()
 		str = strings.Replace(str, "\x00", " ", -1)
 		lines[i] = str
 	}
@@ -749,42 +2142,75 @@ func (v *View) ViewBufferLines() []string {
 
 // LinesHeight is the count of view lines (i.e. lines excluding wrapping)
 func (v *View) LinesHeight() int {
-	return len(v.lines)
+	return v.lines.len()
+}
+
+// MaxLineWidth returns the number of screen columns of the widest line of the
+// view's internal buffer, tabs are expanded up to the next tab stop and wide
+// runes count for two columns. The width is cached until the buffer changes.
+func (v *View) MaxLineWidth() int {
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	return v.maxLineWidth()
 }
 
 // ViewLinesHeight is the count of view lines (i.e. lines including wrapping)
 func (v *View) ViewLinesHeight() int {
-	if !v.tainted && v.contentCache != nil && len(v.contentCache) > 0 {
-		// Use the cache if availabe, it's just a bit faster than re-calculating all frame cells
-		return v.contentCache[len(v.contentCache)-1].y + 1
-	}
+	// The content cache only holds the visible lines
+	return len(v.viewLines())
+}
+
+// LineCount returns the number of logical lines of the view's internal
+// buffer.
+func (v *View) LineCount() int {
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	return v.lines.len()
+}
+
+// VisualRowCount returns the number of screen rows used by the content of the
+// view with its current width. With Wrap, a line wider than the view takes
+// several rows.
+func (v *View) VisualRowCount() int {
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
 	return len(v.viewLines())
 }
 
 // ViewBuffer returns a string with the contents of the view's buffer that is
 // shown to the user.
 func (v *View) ViewBuffer() string {
-	return linesToString(v.viewLines())
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+	viewLines := v.viewLines()
+	lines := make([][]cell, len(viewLines))
+	for i, vline := range viewLines {
+		lines[i] = vline.line
+	}
+	return linesToString(lines)
 }
 
 // Line returns a string with the line of the view's internal buffer
 // at the position corresponding to the point (x, y).
 func (v *View) Line(y int) (string, error) {
-	if y < 0 || y >= len(v.lines) {
+	v.writeMutex.Lock()
+	defer v.writeMutex.Unlock()
+
+	if y < 0 || y >= v.lines.len() {
 		return "", ErrInvalidPoint
 	}
 
-	return lineType(v.lines[y]).String(), nil
+	return lineType(v.lines.line(y)).String(), nil
 }
 
 // Word returns a string with the word of the view's internal buffer
 // at the position corresponding to the point (x, y).
 func (v *View) Word(x, y int) (string, error) {
-	if x < 0 || y < 0 || y >= len(v.lines) || x >= len(v.lines[y]) {
+	if x < 0 || y < 0 || y >= v.lines.len() || x >= len(v.lines.line(y)) {
 		return "", ErrInvalidPoint
 	}
 
-	str := lineType(v.lines[y]).String()
+	str := lineType(v.lines.line(y)).String()
 
 	nl := strings.LastIndexFunc(str[:x], indexFunc)
 	if nl == -1 {
@@ -807,32 +2233,69 @@ func indexFunc(r rune) bool {
 	return r == ' ' || r == 0
 }
 
+// CursorWord returns the word of the view's internal buffer under the
+// cursor and the x position where it starts. If the cursor is right after a
+// word, e.g. while typing it, this word is returned. Words are separated as
+// in the word-wise editing helpers, see WordDelimiter. An empty string is
+// returned if there is no word at the cursor.
+func (v *View) CursorWord() (word string, startX int) {
+	x, y := v.cx, v.cy
+	if y >= v.lines.len() {
+		return "", x
+	}
+	line := v.lines.line(y)
+	if x > len(line) {
+		return "", x
+	}
+	if x == len(line) || v.isWordDelimiter(line[x].chr) {
+		if x == 0 || v.isWordDelimiter(line[x-1].chr) {
+			return "", x
+		}
+	}
+
+	start, end := x, x
+	for start > 0 && !v.isWordDelimiter(line[start-1].chr) {
+		start--
+	}
+	for end < len(line) && !v.isWordDelimiter(line[end].chr) {
+		end++
+	}
+	return lineType(line[start:end]).String(), start
+}
+
 // SetLine changes the contents of an existing line.
 func (v *View) SetLine(y int, text string) error {
-	if y < 0 || y >= len(v.lines) {
+	v.writeMutex.Lock()
+	if y < 0 || y >= v.lines.len() {
+		v.writeMutex.Unlock()
 		err := ErrInvalidPoint
 		return err
 	}
 
 	v.tainted = true
+	v.modified = true
+	v.hasMaxWidth = false
+	v.resetUndo()
 	line := make([]cell, 0)
 	for _, r := range text {
 		c := v.parseInput(r)
 		line = append(line, c...)
 	}
-	v.lines[y] = line
+	v.lines.set(y, line)
+	v.writeMutex.Unlock()
+	v.bufferWritten()
 	return nil
 }
 
 // SetHighlight toggles highlighting of separate lines, for custom lists
 // or multiple selection in views.
 func (v *View) SetHighlight(y int, on bool) error {
-	if y < 0 || y >= len(v.lines) {
+	if y < 0 || y >= v.lines.len() {
 		err := ErrInvalidPoint
 		return err
 	}
 
-	line := v.lines[y]
+	line := v.lines.line(y)
 	cells := make([]cell, 0)
 	for _, c := range line {
 		if on {
@@ -845,22 +2308,77 @@ func (v *View) SetHighlight(y int, on bool) error {
 		cells = append(cells, c)
 	}
 	v.tainted = true
-	v.lines[y] = cells
+	v.lines.set(y, cells)
 	return nil
 }
 
-func lineWidth(line []cell) (n int) {
+// lineWidth returns the number of screen columns used by the line, the
+// line is assumed to start at a tab stop.
+func (v *View) lineWidth(line []cell) (n int) {
 	for i := range line {
-		if line[i].chr == 0 {
-			n++ // if it's NULL character, it's translated to SPACE in setRune
-		} else {
-			n += runewidth.RuneWidth(line[i].chr)
-		}
+		n += v.cellWidth(line[i], n)
 	}
 
 	return
 }
 
+// maxLineWidth returns the screen width of the widest line of the view, it's
+// only computed again once the buffer or the tab width changed.
+func (v *View) maxLineWidth() int {
+	if v.hasMaxWidth && v.maxWidthTab == v.tabWidth() {
+		return v.maxWidth
+	}
+
+	n := 0
+	for y := 0; y < v.lines.len(); y++ {
+		if w := v.lineWidth(v.lines.line(y)); w > n {
+			n = w
+		}
+	}
+	v.maxWidth, v.maxWidthTab, v.hasMaxWidth = n, v.tabWidth(), true
+	return n
+}
+
+// lineColumn returns the screen column of the cell (x, y) of the view's
+// internal buffer, relative to the start of the line. Points beyond the end
+// of the line are one column wide.
+func (v *View) lineColumn(x, y int) int {
+	if y >= v.lines.len() {
+		return x
+	}
+	line := v.lines.line(y)
+	if x > len(line) {
+		return v.lineWidth(line) + x - len(line)
+	}
+	return v.lineWidth(line[:x])
+}
+
+// cellWidth returns the number of screen columns used by c when it's drawn
+// at the column col.
+func (v *View) cellWidth(c cell, col int) int {
+	if v.Mask != 0 {
+		return 1 // every cell is drawn as one mask rune
+	}
+	switch c.chr {
+	case 0:
+		return 1 // if it's NULL character, it's translated to SPACE in setRune
+	case '\t':
+		return v.tabWidth() - col%v.tabWidth()
+	}
+	if w := runewidth.RuneWidth(c.chr); w > 0 {
+		return w
+	}
+	return 1 // zero width runes without base character are drawn on a space
+}
+
+// tabWidth returns the distance between two tab stops.
+func (v *View) tabWidth() int {
+	if v.TabWidth <= 0 {
+		return defaultTabWidth
+	}
+	return v.TabWidth
+}
+
 // takeLine slices one visable line from l and returns the sliced part
 func (v *View) takeLine(l *[]cell) (visableLine []cell, width int, end bool) {
 	if l == nil {
@@ -880,13 +2398,10 @@ func (v *View) takeLine(l *[]cell) (visableLine []cell, width int, end bool) {
 	cell := cell{}
 
 	for i, cell = range *l {
-		chr := cell.chr
-		charWidth := 1 // default for NULL character (translated to SPACE in setRune)
-		if chr != 0 {
-			charWidth = runewidth.RuneWidth(chr)
-		}
+		charWidth := v.cellWidth(cell, width)
 
-		if width+charWidth > maxX {
+		// The first character is always taken, even if it's too wide
+		if width+charWidth > maxX && width > 0 {
 			i-- // decrease as this character is not included
 			break
 		}
@@ -899,12 +2414,30 @@ func (v *View) takeLine(l *[]cell) (visableLine []cell, width int, end bool) {
 	}
 
 	i++
+
+	// Break the line after the last whitespace instead of inside a word
+	if v.WrapWords && i < len(*l) && !isSpaceCell((*l)[i]) {
+		for k := len(visableLine) - 1; k >= 0; k-- {
+			if isSpaceCell(visableLine[k]) {
+				visableLine = visableLine[:k+1]
+				width = v.lineWidth(visableLine)
+				i = k + 1
+				break
+			}
+		}
+	}
+
 	end = i == len(*l)
 	*l = (*l)[i:]
 
 	return
 }
 
+// isSpaceCell reports whether c is displayed as whitespace.
+func isSpaceCell(c cell) bool {
+	return c.chr == 0 || unicode.IsSpace(c.chr)
+}
+
 func linesToString(lines [][]cell) string {
 	str := make([]string, len(lines))
 	for i := range lines {
@@ -0,0 +1,1689 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+// drawTestView draws v on a new simulated screen.
+func drawTestView(t *testing.T, v *View) {
+	t.Helper()
+	if err := tcellInitSimulation(); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.draw(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// viewCell returns the rune and the style drawn at the point (x, y) of the
+// content area of v.
+func viewCell(v *View, x, y int) (rune, tcell.Style) {
+	ch, _, st, _ := screen.GetContent(v.x0+x+1, v.y0+y+1)
+	return ch, st
+}
+
+func TestSetContent(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      []string
+		wantX, wantY int
+	}{
+		{"empty", []string{}, 0, 0},
+		{"multi line", []string{"hello", "big", "world"}, 3, 1},
+		{"tabs", []string{"\tfoo\tbar"}, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, "first line", "second line")
+			v.SetCursor(5, 1)
+			v.SetContent(tt.content)
+
+			if got := v.Lines(); !reflect.DeepEqual(got, tt.content) {
+				t.Errorf("Expected lines to be: %q got: %q", tt.content, got)
+			}
+			if x, y := v.Cursor(); x != tt.wantX || y != tt.wantY {
+				t.Errorf("Expected cursor to be at (%d, %d) got: (%d, %d)", tt.wantX, tt.wantY, x, y)
+			}
+			if !v.IsTainted() {
+				t.Error("Expected view to be tainted")
+			}
+		})
+	}
+}
+
+func TestCell(t *testing.T) {
+	v := newTestView(10, 5)
+	v.SetContent([]string{"ab", "c"})
+
+	for _, tt := range []struct {
+		x, y   int
+		wantCh rune
+		wantOK bool
+	}{
+		{0, 0, 'a', true},
+		{1, 0, 'b', true},
+		{0, 1, 'c', true},
+		{2, 0, 0, false},
+		{1, 1, 0, false},
+		{0, 2, 0, false},
+		{-1, 0, 0, false},
+	} {
+		if ch, _, _, ok := v.Cell(tt.x, tt.y); ch != tt.wantCh || ok != tt.wantOK {
+			t.Errorf("Expected cell (%d, %d) to be (%q, %v) got: (%q, %v)", tt.x, tt.y, tt.wantCh, tt.wantOK, ch, ok)
+		}
+	}
+
+	// Replace the last cell of a line
+	if err := v.SetCell(1, 0, 'x', ColorRed, ColorBlue); err != nil {
+		t.Fatal(err)
+	}
+	if ch, fg, bg, ok := v.Cell(1, 0); ch != 'x' || fg != ColorRed || bg != ColorBlue || !ok {
+		t.Errorf("Expected cell (1, 0) to be ('x', %v, %v) got: (%q, %v, %v, %v)", ColorRed, ColorBlue, ch, fg, bg, ok)
+	}
+
+	// Beyond the end of the buffer
+	if err := v.SetCell(3, 3, 'y', ColorGreen, ColorDefault); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := v.Lines(), []string{"ax", "c", "", "   y"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected lines %q got: %q", want, got)
+	}
+	if ch, _, _, ok := v.Cell(2, 3); ch != 0 || !ok {
+		t.Errorf("Expected an empty cell before the written one got: (%q, %v)", ch, ok)
+	}
+	if ch, fg, _, ok := v.Cell(3, 3); ch != 'y' || fg != ColorGreen || !ok {
+		t.Errorf("Expected cell (3, 3) to be 'y' got: (%q, %v, %v)", ch, fg, ok)
+	}
+
+	if err := v.SetCell(-1, 0, 'z', ColorDefault, ColorDefault); err != ErrInvalidPoint {
+		t.Errorf("Expected ErrInvalidPoint got: %v", err)
+	}
+}
+
+func TestFillRect(t *testing.T) {
+	v := newTestView(10, 5)
+	v.SetContent([]string{"abcdef", "gh"})
+
+	v.FillRect(1, 0, 3, 3, '#', ColorRed, ColorBlue)
+	if got, want := v.Lines(), []string{"a###ef", "g###", " ###"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected lines %q got: %q", want, got)
+	}
+	for y := 0; y < 3; y++ {
+		for x := 1; x < 4; x++ {
+			if ch, fg, bg, ok := v.Cell(x, y); ch != '#' || fg != ColorRed || bg != ColorBlue || !ok {
+				t.Errorf("Expected cell (%d, %d) to be filled got: (%q, %v, %v, %v)", x, y, ch, fg, bg, ok)
+			}
+		}
+	}
+	if _, fg, _, _ := v.Cell(4, 0); fg != ColorDefault {
+		t.Errorf("Expected the cell after the rectangle to be kept got color: %v", fg)
+	}
+
+	// Clipped to the non-negative coordinates
+	v.FillRect(-2, -1, 3, 2, '*', ColorDefault, ColorDefault)
+	if got, want := v.Lines(), []string{"*###ef", "g###", " ###"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected lines %q got: %q", want, got)
+	}
+	v.FillRect(-5, 0, 3, 2, '*', ColorDefault, ColorDefault)
+	v.FillRect(0, 0, 0, 2, '*', ColorDefault, ColorDefault)
+	if got, want := v.Lines(), []string{"*###ef", "g###", " ###"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected empty rectangles to change nothing got: %q", got)
+	}
+}
+
+func TestDrawLines(t *testing.T) {
+	v := newTestView(10, 5)
+	v.SetContent([]string{"abc"})
+	v.FgColor, v.BgColor = ColorYellow, ColorBlack
+
+	v.DrawHLine(1, 0, 4, '─')
+	v.DrawVLine(2, 1, 3, '│')
+	if got, want := v.Lines(), []string{"a────", "  │", "  │", "  │"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected lines %q got: %q", want, got)
+	}
+	for _, p := range []struct {
+		x, y int
+		ch   rune
+	}{{1, 0, '─'}, {4, 0, '─'}, {2, 1, '│'}, {2, 3, '│'}} {
+		if ch, fg, bg, ok := v.Cell(p.x, p.y); ch != p.ch || fg != ColorYellow || bg != ColorBlack || !ok {
+			t.Errorf("Expected cell (%d, %d) to be %q with the view's colors got: (%q, %v, %v, %v)", p.x, p.y, p.ch, ch, fg, bg, ok)
+		}
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	const writers, n = 4, 100
+	v := newTestView(10, 5)
+	if err := tcellInitSimulation(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				if w%2 == 0 {
+					fmt.Fprintf(v, "w%d-%d\n", w, i)
+				} else {
+					v.WriteString(fmt.Sprintf("w%d-%d\n", w, i))
+				}
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for drawing := true; drawing; {
+		select {
+		case <-done:
+			drawing = false
+		default:
+		}
+		if err := v.draw(); err != nil {
+			t.Fatal(err)
+		}
+		_ = v.Buffer()
+	}
+
+	seen := map[string]bool{}
+	for _, l := range v.Lines() {
+		if l != "" {
+			seen[l] = true
+		}
+	}
+	for w := 0; w < writers; w++ {
+		for i := 0; i < n; i++ {
+			if l := fmt.Sprintf("w%d-%d", w, i); !seen[l] {
+				t.Fatalf("line %q is missing or corrupted", l)
+			}
+		}
+	}
+	if len(seen) != writers*n {
+		t.Errorf("got %d distinct lines, want %d", len(seen), writers*n)
+	}
+}
+
+func TestMaxLines(t *testing.T) {
+	v := newTestView(10, 3)
+	v.MaxLines = 5
+	for i := 0; i < 5; i++ {
+		fmt.Fprintf(v, "l%d\n", i)
+	}
+	v.SetCursor(1, 4)
+	v.SetOrigin(0, 3)
+	v.SetSelection(0, 1, 2, 3)
+	for i := 5; i < 8; i++ {
+		fmt.Fprintf(v, "l%d\n", i)
+	}
+
+	want := []string{"l3", "l4", "l5", "l6", "l7", ""}
+	if got := v.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got lines %q, want %q", got, want)
+	}
+	if cx, cy := v.Cursor(); cx != 1 || cy != 1 {
+		t.Errorf("got cursor (%d, %d), want (1, 1)", cx, cy)
+	}
+	if _, oy := v.Origin(); oy != 0 {
+		t.Errorf("got y-origin %d, want 0", oy)
+	}
+	if got := v.SelectedText(); got != "l3" {
+		t.Errorf("got selection %q, want %q", got, "l3")
+	}
+
+	// Writing continues after the last line
+	fmt.Fprint(v, "l8")
+	want = []string{"l4", "l5", "l6", "l7", "l8"}
+	if got := v.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got lines %q, want %q", got, want)
+	}
+	if cx, cy := v.Cursor(); cx != 1 || cy != 0 {
+		t.Errorf("got cursor (%d, %d), want (1, 0)", cx, cy)
+	}
+}
+
+func TestMaxLinesAutoscroll(t *testing.T) {
+	v := newTestView(4, 2)
+	v.MaxLines = 3
+	v.Wrap = true
+	v.Autoscroll = true
+	for i := 0; i < 6; i++ {
+		v.AppendLine(fmt.Sprintf("line%d", i))
+	}
+
+	want := []string{"line3", "line4", "line5"}
+	if got := v.Lines(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got lines %q, want %q", got, want)
+	}
+	if _, oy := v.Origin(); oy != 4 {
+		t.Errorf("got y-origin %d, want 4", oy)
+	}
+	drawTestView(t, v)
+	if got := v.ViewBufferLines()[4:]; !reflect.DeepEqual(got, []string{"line", "5"}) {
+		t.Errorf("got last rows %q", got)
+	}
+}
+
+func TestLinesTrimsPadding(t *testing.T) {
+	v := newTestView(20, 5)
+	v.SetWritePos(2, 1)
+	v.WriteString("foo")
+	v.lines.set(0, append(v.lines.line(0), make([]cell, 3)...))
+
+	want := []string{"", "  foo"}
+	if got := v.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected lines to be: %q got: %q", want, got)
+	}
+}
+
+func TestReader(t *testing.T) {
+	v := newTestView(10, 5)
+	v.Write([]byte("first\n\nthird\n\x1b[31mred\x1b[0m"))
+	// Pad the first line with empty cells
+	v.lines.set(0, append(v.lines.line(0), cell{}, cell{}))
+
+	r := v.Reader()
+	fmt.Fprint(v, " ignored")
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), "first\n\nthird\nred"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestSaveRestoreState(t *testing.T) {
+	v := newTestView(5, 2, "zero", "one", "two", "three", "four")
+	v.SetOrigin(0, 2)
+	v.SetCursor(3, 3)
+	v.SetSelection(1, 2, 2, 3)
+	state := v.SaveState()
+
+	v.SetContent([]string{"other", "text"})
+	v.SetCursor(1, 1)
+	v.ClearSelection()
+
+	// The buffer hasn't shrunk enough to move anything
+	v.SetContent([]string{"a", "b", "c", "four", "e"})
+	v.RestoreState(state)
+	if cx, cy := v.Cursor(); cx != 3 || cy != 3 {
+		t.Errorf("got cursor (%d, %d), want (3, 3)", cx, cy)
+	}
+	if ox, oy := v.Origin(); ox != 0 || oy != 2 {
+		t.Errorf("got origin (%d, %d), want (0, 2)", ox, oy)
+	}
+	if got := v.SelectedText(); got != "\nfo" {
+		t.Errorf("got selection %q, want %q", got, "\nfo")
+	}
+
+	// The buffer has shrunk: everything is clipped
+	v.SetContent([]string{"ab", "cd"})
+	v.RestoreState(state)
+	if cx, cy := v.Cursor(); cx != 2 || cy != 1 {
+		t.Errorf("got cursor (%d, %d), want (2, 1)", cx, cy)
+	}
+	if ox, oy := v.Origin(); ox != 0 || oy != 0 {
+		t.Errorf("got origin (%d, %d), want (0, 0)", ox, oy)
+	}
+	if got := v.SelectedText(); got != "d" {
+		t.Errorf("got selection %q, want %q", got, "d")
+	}
+}
+
+func TestTabPosOnScreen(t *testing.T) {
+	tests := []struct {
+		name         string
+		tabWidth     int
+		wrap         bool
+		x, y         int
+		wantX, wantY int
+	}{
+		{"tab at column 0", 4, false, 1, 0, 4, 0},
+		{"after text", 4, false, 3, 0, 6, 0},
+		{"after partial tab", 4, false, 4, 0, 8, 0},
+		{"past end of line", 4, false, 6, 0, 10, 0},
+		{"width 8", 8, false, 4, 0, 16, 0},
+		{"tab stop", 0, false, 2, 1, 16, 1},
+		{"wrapped", 4, true, 4, 0, 4, 1},
+		{"wrapped tab", 4, true, 3, 0, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, "\tab\tc", "\t\tx")
+			if tt.wrap {
+				v = newTestView(6, 5, "\tab\tc")
+				v.Wrap = true
+			}
+			if tt.tabWidth > 0 {
+				v.TabWidth = tt.tabWidth
+			}
+			x, y, _ := v.linesPosOnScreen(tt.x, tt.y)
+			if x != tt.wantX || y != tt.wantY {
+				t.Errorf("Expected (%d, %d) to be on screen at (%d, %d) got: (%d, %d)", tt.x, tt.y, tt.wantX, tt.wantY, x, y)
+			}
+		})
+	}
+}
+
+func TestTabDraw(t *testing.T) {
+	v := newTestView(20, 5, "\tab\tc", "xy\tz")
+	v.TabWidth = 4
+	drawTestView(t, v)
+
+	want := []string{"    ab  c", "xy  z"}
+	for y, line := range want {
+		for x, r := range line {
+			if ch, _ := viewCell(v, x, y); ch != r {
+				t.Errorf("Expected %q at (%d, %d) got: %q", r, x, y, ch)
+			}
+		}
+	}
+
+	t.Run("cursor follows tab stops", func(t *testing.T) {
+		v.SetCursor(0, 1)
+		v.MoveCursor(3, 0)
+		if x, _, _ := v.linesPosOnScreen(v.cx, v.cy); x != 4 {
+			t.Errorf("Expected cursor on screen at column 4 got: %d", x)
+		}
+	})
+
+	t.Run("origin", func(t *testing.T) {
+		v := newTestView(5, 2, "\tabcdef")
+		v.TabWidth = 4
+		for i := 0; i < 7; i++ {
+			v.MoveCursor(1, 0)
+		}
+		if ox, _ := v.Origin(); ox != 6 {
+			t.Fatalf("Expected origin x to be 6 got: %d", ox)
+		}
+		drawTestView(t, v)
+		for x, r := range "cdef" {
+			if ch, _ := viewCell(v, x, 0); ch != r {
+				t.Errorf("Expected %q at (%d, 0) got: %q", r, x, ch)
+			}
+		}
+	})
+
+	t.Run("written tab", func(t *testing.T) {
+		v := newTestView(20, 2)
+		v.TabWidth = 8
+		fmt.Fprint(v, "a\tb")
+		if line, _ := v.Line(0); line != "a\tb" {
+			t.Errorf("Expected the tab to be stored got: %q", line)
+		}
+		drawTestView(t, v)
+		for x, r := range "a       b" {
+			if ch, _ := viewCell(v, x, 0); ch != r {
+				t.Errorf("Expected %q at (%d, 0) got: %q", r, x, ch)
+			}
+		}
+		if w := v.MaxLineWidth(); w != 9 {
+			t.Errorf("Expected a width of 9 got: %d", w)
+		}
+	})
+}
+
+func TestWideRunes(t *testing.T) {
+	t.Run("position on screen", func(t *testing.T) {
+		v := newTestView(20, 5, "a世b界")
+		for x, want := range []int{0, 1, 3, 4, 6, 7} {
+			if got, _, _ := v.linesPosOnScreen(x, 0); got != want {
+				t.Errorf("Expected cell %d to be on screen at column %d got: %d", x, want, got)
+			}
+		}
+	})
+
+	t.Run("wrapped position on screen", func(t *testing.T) {
+		v := newTestView(4, 5, "ab世界c")
+		v.Wrap = true
+		for x, want := range [][2]int{{0, 0}, {1, 0}, {2, 0}, {0, 1}, {2, 1}, {3, 1}} {
+			if gotX, gotY, _ := v.linesPosOnScreen(x, 0); gotX != want[0] || gotY != want[1] {
+				t.Errorf("Expected cell %d to be on screen at %v got: [%d %d]", x, want, gotX, gotY)
+			}
+		}
+	})
+
+	t.Run("backspace", func(t *testing.T) {
+		v := newTestView(20, 5, "a世b")
+		v.SetCursor(2, 0)
+		v.EditDelete(true)
+		assertBuffer(t, v, 1, 0, "ab")
+		if x, _, _ := v.linesPosOnScreen(v.cx, v.cy); x != 1 {
+			t.Errorf("Expected cursor on screen at column 1 got: %d", x)
+		}
+	})
+
+	t.Run("draw", func(t *testing.T) {
+		v := newTestView(20, 5, "a世b界c")
+		drawTestView(t, v)
+		for x, want := range map[int]rune{0: 'a', 1: '世', 3: 'b', 4: '界', 6: 'c'} {
+			if ch, _ := viewCell(v, x, 0); ch != want {
+				t.Errorf("Expected %q at column %d got: %q", want, x, ch)
+			}
+		}
+	})
+
+	t.Run("cut by the right edge", func(t *testing.T) {
+		v := newTestView(4, 5, "abc世")
+		drawTestView(t, v)
+		if ch, _ := viewCell(v, 3, 0); ch != ' ' {
+			t.Errorf("Expected ' ' at column 3 got: %q", ch)
+		}
+	})
+
+	t.Run("cursor keeps the wide rune visible", func(t *testing.T) {
+		v := newTestView(4, 5, "abc世d")
+		for i := 0; i < 3; i++ {
+			v.MoveCursor(1, 0)
+		}
+		if ox, _ := v.Origin(); ox != 1 {
+			t.Errorf("Expected origin x to be 1 got: %d", ox)
+		}
+		drawTestView(t, v)
+		if ch, _ := viewCell(v, 2, 0); ch != '世' {
+			t.Errorf("Expected '世' at column 2 got: %q", ch)
+		}
+	})
+}
+
+func TestWriteStyled(t *testing.T) {
+	v := newTestView(20, 5)
+	v.FgColor = ColorRed
+	v.WriteStyled("ab", AttrBold|AttrUnderline)
+	v.WriteString("c")
+	v.WriteStyled("\x1b[32md\n", ColorBlue|AttrReverse)
+
+	want := []Attribute{
+		ColorDefault | AttrBold | AttrUnderline,
+		ColorDefault | AttrBold | AttrUnderline,
+		ColorDefault,
+		ColorGreen | AttrReverse,
+	}
+	for x, fg := range want {
+		if got := v.lines.line(0)[x].fgColor; got != fg {
+			t.Errorf("Expected foreground of cell %d to be %x got: %x", x, fg, got)
+		}
+	}
+
+	drawTestView(t, v)
+	wantStyles := []tcell.Style{
+		getTcellStyle(ColorRed|AttrBold|AttrUnderline, ColorDefault, OutputNormal),
+		getTcellStyle(ColorRed|AttrBold|AttrUnderline, ColorDefault, OutputNormal),
+		getTcellStyle(ColorRed, ColorDefault, OutputNormal),
+		getTcellStyle(ColorGreen|AttrReverse, ColorDefault, OutputNormal),
+	}
+	for x, st := range wantStyles {
+		if _, got := viewCell(v, x, 0); got != st {
+			t.Errorf("Expected style of cell %d to be %v got: %v", x, st, got)
+		}
+	}
+}
+
+func TestWriteColored(t *testing.T) {
+	v := newTestView(20, 5)
+	v.FgColor, v.BgColor = ColorRed, ColorBlack
+	v.WriteString("\x1b[33ma")
+	v.WriteColored(ColorGreen, ColorBlue, "bc")
+	v.WriteColored(ColorWhite|AttrBold, ColorMagenta, "d\ne")
+	v.WriteString("f")
+
+	want := [][]struct {
+		ch     rune
+		fg, bg Attribute
+	}{
+		{
+			{'a', ColorYellow, ColorDefault},
+			{'b', ColorGreen, ColorBlue},
+			{'c', ColorGreen, ColorBlue},
+			{'d', ColorWhite | AttrBold, ColorMagenta},
+		},
+		{
+			{'e', ColorWhite | AttrBold, ColorMagenta},
+			{'f', ColorYellow, ColorDefault},
+		},
+	}
+	for y, line := range want {
+		for x, w := range line {
+			ch, fg, bg, ok := v.Cell(x, y)
+			if !ok || ch != w.ch || fg != w.fg || bg != w.bg {
+				t.Errorf("Expected cell (%d, %d) to be %q %x %x got: %q %x %x", x, y, w.ch, w.fg, w.bg, ch, fg, bg)
+			}
+		}
+	}
+	if v.FgColor != ColorRed || v.BgColor != ColorBlack {
+		t.Errorf("Expected the view colors to be unchanged got: %x %x", v.FgColor, v.BgColor)
+	}
+}
+
+func TestEllipsisOverflow(t *testing.T) {
+	tests := []struct {
+		name   string
+		line   string
+		ox     int
+		wrap   bool
+		want   string
+		wantFg Attribute
+	}{
+		{"shorter", "abc", 0, false, "abc  ", ColorDefault},
+		{"at width", "abcde", 0, false, "abcde", ColorDefault},
+		{"over width", "abcdef", 0, false, "abcd…", ColorDefault},
+		{"scrolled to the end", "abcdef", 1, false, "bcdef", ColorDefault},
+		{"scrolled", "abcdefgh", 1, false, "bcde…", ColorDefault},
+		{"wide rune at the edge", "abc世界", 0, false, "abc …", ColorDefault},
+		{"colored", "abcd\x1b[31mef", 0, false, "abcd…", ColorRed},
+		{"wrapped", "abcdef", 0, true, "abcde", ColorDefault},
+	}
+	for _, tt := range tests {
+		v := newTestView(5, 2)
+		v.EllipsisOverflow = true
+		v.Wrap = tt.wrap
+		fmt.Fprint(v, tt.line)
+		v.SetOrigin(tt.ox, 0)
+		buffer := v.Buffer()
+		drawTestView(t, v)
+
+		if got := viewRow(v, 0); got != tt.want {
+			t.Errorf("%s: expected %q got: %q", tt.name, tt.want, got)
+		}
+		if _, st := viewCell(v, 4, 0); st != getTcellStyle(tt.wantFg, ColorDefault, OutputNormal) {
+			t.Errorf("%s: expected the last cell to be drawn with %x", tt.name, tt.wantFg)
+		}
+		if got := v.Buffer(); got != buffer {
+			t.Errorf("%s: expected the buffer %q got: %q", tt.name, buffer, got)
+		}
+	}
+
+	// Editable views show the content
+	v := newTestView(5, 2, "abcdef")
+	v.EllipsisOverflow = true
+	v.Editable = true
+	drawTestView(t, v)
+	if got := viewRow(v, 0); got != "abcde" {
+		t.Errorf("Editable: expected %q got: %q", "abcde", got)
+	}
+}
+
+func TestWrapWords(t *testing.T) {
+	tests := []struct {
+		name  string
+		width int
+		line  string
+		want  []string
+	}{
+		{"fits", 20, "hello big world", []string{"hello big world"}},
+		{"break after space", 10, "hello big world", []string{"hello big ", "world"}},
+		{"narrow", 6, "hello big world", []string{"hello ", "big ", "world"}},
+		{"break at space", 5, "hello big world", []string{"hello", " big ", "world"}},
+		{"long token", 4, "abcdefghij k", []string{"abcd", "efgh", "ij k"}},
+		{"long token after word", 6, "ab cdefghij", []string{"ab ", "cdefgh", "ij"}},
+		{"multiple spaces", 6, "ab   cdef", []string{"ab   ", "cdef"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(tt.width, 5, tt.line)
+			v.Wrap = true
+			v.WrapWords = true
+			if got := v.ViewBufferLines(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected view lines to be: %q got: %q", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("cursor", func(t *testing.T) {
+		v := newTestView(10, 5, "hello big world", "next")
+		v.Wrap = true
+		v.WrapWords = true
+		for x, want := range map[int][2]int{0: {0, 0}, 9: {9, 0}, 10: {0, 1}, 14: {4, 1}, 15: {5, 1}} {
+			if gotX, gotY, _ := v.linesPosOnScreen(x, 0); gotX != want[0] || gotY != want[1] {
+				t.Errorf("Expected cell %d to be on screen at %v got: [%d %d]", x, want, gotX, gotY)
+			}
+		}
+		if _, y, _ := v.linesPosOnScreen(0, 1); y != 2 {
+			t.Errorf("Expected next line on screen at row 2 got: %d", y)
+		}
+
+		v.SetCursor(9, 0)
+		v.MoveCursor(1, 0)
+		if x, y, _ := v.linesPosOnScreen(v.cx, v.cy); x != 0 || y != 1 {
+			t.Errorf("Expected cursor on screen at (0, 1) got: (%d, %d)", x, y)
+		}
+	})
+
+	t.Run("without word wrap", func(t *testing.T) {
+		v := newTestView(10, 5, "hello big world")
+		v.Wrap = true
+		want := []string{"hello big ", "world"}
+		if got := v.ViewBufferLines(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected view lines to be: %q got: %q", want, got)
+		}
+		v = newTestView(6, 5, "hello big world")
+		v.Wrap = true
+		want = []string{"hello ", "big wo", "rld"}
+		if got := v.ViewBufferLines(); !reflect.DeepEqual(got, want) {
+			t.Errorf("Expected view lines to be: %q got: %q", want, got)
+		}
+	})
+}
+
+func TestAutoscrollResize(t *testing.T) {
+	tests := []struct {
+		name          string
+		wrap          bool
+		width, height int
+	}{
+		{"shorter", false, 20, 3},
+		{"taller", false, 20, 8},
+		{"narrower wrapped", true, 6, 5},
+		{"narrower and shorter wrapped", true, 4, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5)
+			v.Autoscroll = true
+			v.Wrap = tt.wrap
+			for i := 0; i < 10; i++ {
+				fmt.Fprintf(v, "line %d end\n", i)
+			}
+			drawTestView(t, v)
+
+			v.x1, v.y1 = v.x0+tt.width+1, v.y0+tt.height+1
+			v.tainted = true
+			drawTestView(t, v)
+
+			// The last view line must be at the bottom, when the content is
+			// higher than the view
+			want := v.ViewBufferLines()
+			want = want[:len(want)-1]
+			row := tt.height - 1
+			if len(want) < tt.height {
+				row = len(want) - 1
+			}
+			for x, r := range want[len(want)-1] {
+				if ch, _ := viewCell(v, x, row); ch != r {
+					t.Fatalf("Expected last line %q on row %d, got %q at column %d", want[len(want)-1], row, ch, x)
+				}
+			}
+		})
+	}
+
+	t.Run("without trailing newline", func(t *testing.T) {
+		v := newTestView(20, 2)
+		v.Autoscroll = true
+		fmt.Fprint(v, "a\nb\nc")
+		drawTestView(t, v)
+		if ch, _ := viewCell(v, 0, 1); ch != 'c' {
+			t.Errorf("Expected last line on the last row got: %q", ch)
+		}
+	})
+}
+
+func TestCursorWord(t *testing.T) {
+	lines := []string{"foo  bar.baz", ""}
+	tests := []struct {
+		name      string
+		cx, cy    int
+		want      string
+		wantStart int
+	}{
+		{"start of word", 0, 0, "foo", 0},
+		{"middle of word", 1, 0, "foo", 0},
+		{"end of word", 2, 0, "foo", 0},
+		{"after word", 3, 0, "foo", 0},
+		{"whitespace", 4, 0, "", 4},
+		{"start of next word", 5, 0, "bar", 5},
+		{"punctuation", 8, 0, "bar", 5},
+		{"after punctuation", 9, 0, "baz", 9},
+		{"end of line", 12, 0, "baz", 9},
+		{"empty line", 0, 1, "", 0},
+		{"past end of buffer", 0, 3, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, lines...)
+			v.SetCursorUnrestricted(tt.cx, tt.cy)
+			if word, start := v.CursorWord(); word != tt.want || start != tt.wantStart {
+				t.Errorf("Expected word %q at %d got: %q at %d", tt.want, tt.wantStart, word, start)
+			}
+		})
+	}
+
+	t.Run("custom delimiter", func(t *testing.T) {
+		v := newTestView(20, 5, "foo bar.baz")
+		v.WordDelimiter = func(r rune) bool { return r == ' ' }
+		v.SetCursor(6, 0)
+		if word, start := v.CursorWord(); word != "bar.baz" || start != 4 {
+			t.Errorf("Expected word %q at %d got: %q at %d", "bar.baz", 4, word, start)
+		}
+	})
+}
+
+func TestAppendLine(t *testing.T) {
+	t.Run("keeps origin", func(t *testing.T) {
+		v := newTestView(20, 3, "a", "b", "c", "d", "e")
+		v.SetOrigin(0, 1)
+		v.SetCursor(0, 1)
+		for i := 0; i < 5; i++ {
+			v.AppendLine(fmt.Sprintf("line %d", i))
+		}
+		if n := v.LinesHeight(); n != 10 {
+			t.Errorf("Expected 10 lines got: %d", n)
+		}
+		if ox, oy := v.Origin(); ox != 0 || oy != 1 {
+			t.Errorf("Expected origin to be (0, 1) got: (%d, %d)", ox, oy)
+		}
+		if x, y := v.Cursor(); x != 0 || y != 1 {
+			t.Errorf("Expected cursor to be (0, 1) got: (%d, %d)", x, y)
+		}
+	})
+
+	t.Run("autoscroll", func(t *testing.T) {
+		v := newTestView(20, 3, "a")
+		v.Autoscroll = true
+		v.AppendLine("b\nc")
+		if _, oy := v.Origin(); oy != 0 {
+			t.Errorf("Expected origin y to be 0 got: %d", oy)
+		}
+		v.AppendLine("d")
+		if _, oy := v.Origin(); oy != 1 {
+			t.Errorf("Expected origin y to be 1 got: %d", oy)
+		}
+	})
+
+	t.Run("styled", func(t *testing.T) {
+		v := newTestView(20, 3)
+		v.AppendLine("\x1b[31mred\x1b[0m")
+		if got := v.BufferLines(); !reflect.DeepEqual(got, []string{"red"}) {
+			t.Errorf("Expected lines to be: %q got: %q", []string{"red"}, got)
+		}
+		if fg := v.lines.line(0)[0].fgColor; fg != ColorRed {
+			t.Errorf("Expected foreground to be red got: %v", fg)
+		}
+	})
+}
+
+func TestOnCursorMove(t *testing.T) {
+	type move struct{ oldX, oldY, newX, newY int }
+
+	tests := []struct {
+		name string
+		do   func(v *View)
+		want []move
+	}{
+		{"SetCursor", func(v *View) { v.SetCursor(2, 1) }, []move{{0, 0, 2, 1}}},
+		{"SetCursor same position", func(v *View) { v.SetCursor(0, 0) }, nil},
+		{"MoveCursor", func(v *View) {
+			v.MoveCursor(1, 0)
+			v.MoveCursor(0, 1)
+		}, []move{{0, 0, 1, 0}, {1, 0, 1, 1}}},
+		{"MoveCursor blocked", func(v *View) { v.MoveCursor(-1, 0) }, nil},
+		{"EditWrite", func(v *View) { v.EditWrite('x') }, []move{{0, 0, 1, 0}}},
+		{"EditWriteString", func(v *View) { v.EditWriteString("a\nbc") }, []move{{0, 0, 2, 1}}},
+		{"EditNewLine", func(v *View) {
+			v.SetCursor(3, 0)
+			v.EditNewLine()
+		}, []move{{0, 0, 3, 0}, {3, 0, 0, 1}}},
+		{"EditDelete without move", func(v *View) { v.EditDelete(false) }, nil},
+		{"editor", func(v *View) { simpleEditor(v, KeyArrowDown, 0, 0) }, []move{{0, 0, 0, 1}}},
+		{"Undo", func(v *View) {
+			v.EditWrite('x')
+			v.Undo()
+		}, []move{{0, 0, 1, 0}, {1, 0, 0, 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, "hello", "world")
+			var got []move
+			v.OnCursorMove = func(v *View, oldX, oldY, newX, newY int) {
+				got = append(got, move{oldX, oldY, newX, newY})
+				if x, y := v.Cursor(); x != newX || y != newY {
+					t.Errorf("Expected cursor to be at (%d, %d) got: (%d, %d)", newX, newY, x, y)
+				}
+			}
+			tt.do(v)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected moves to be: %v got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCursorPosition(t *testing.T) {
+	lines := []string{"first line", "a long line that is wrapped", "", "last"}
+	tests := []struct {
+		name              string
+		wrap              bool
+		cx, cy            int
+		wantLine, wantCol int
+	}{
+		{"start", false, 0, 0, 1, 1},
+		{"scrolled", false, 3, 3, 4, 4},
+		{"scrolled horizontally", false, 20, 1, 2, 21},
+		{"wrapped", true, 20, 1, 2, 21},
+		{"after wrapped line", true, 2, 3, 4, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(8, 2, lines...)
+			v.Wrap = tt.wrap
+			v.SetCursor(0, 0)
+			v.MoveCursor(0, tt.cy)
+			v.MoveCursor(tt.cx, 0)
+			if ox, oy := v.Origin(); !tt.wrap && ox == 0 && oy == 0 && tt.cy > 1 {
+				t.Fatalf("Expected view to be scrolled got origin: (%d, %d)", ox, oy)
+			}
+			if line, col := v.CursorPosition(); line != tt.wantLine || col != tt.wantCol {
+				t.Errorf("Expected position to be %d:%d got: %d:%d", tt.wantLine, tt.wantCol, line, col)
+			}
+		})
+	}
+}
+
+func TestSetCursorFromScreen(t *testing.T) {
+	lines := []string{"first line", "\tb", "a long wrapped line", "世界", "abcdefg世", "last"}
+	tests := []struct {
+		name         string
+		wrap         bool
+		ox, oy       int
+		sx, sy       int
+		wantX, wantY int
+	}{
+		{"first cell", false, 0, 0, 1, 1, 0, 0},
+		{"middle of line", false, 0, 0, 4, 1, 3, 0},
+		{"past end of line", false, 0, 0, 8, 2, 2, 1},
+		{"on tab", false, 0, 0, 3, 2, 0, 1},
+		{"after tab", false, 0, 0, 5, 2, 1, 1},
+		{"wide rune", false, 0, 0, 4, 4, 1, 3},
+		{"scrolled", false, 3, 2, 2, 1, 4, 2},
+		{"scrolled horizontally", false, 4, 0, 1, 1, 4, 0},
+		{"below last line", false, 0, 2, 3, 5, 2, 5},
+		{"wrapped", true, 0, 0, 6, 3, 2, 1},
+		{"wrapped row", true, 0, 0, 3, 5, 10, 2},
+		{"end of wrapped row", true, 0, 4, 8, 4, 6, 4},
+		{"end of wrapped line", true, 0, 1, 8, 5, 19, 2},
+		{"scrolled wrapped", true, 0, 2, 3, 3, 10, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(8, 5, lines...)
+			v.TabWidth = 4
+			v.Wrap = tt.wrap
+			v.ox, v.oy = tt.ox, tt.oy
+			if err := v.SetCursorFromScreen(v.x0+tt.sx, v.y0+tt.sy); err != nil {
+				t.Fatal(err)
+			}
+			if x, y := v.Cursor(); x != tt.wantX || y != tt.wantY {
+				t.Errorf("Expected cursor to be at (%d, %d) got: (%d, %d)", tt.wantX, tt.wantY, x, y)
+			}
+		})
+	}
+
+	t.Run("outside the view", func(t *testing.T) {
+		v := newTestView(8, 5, lines...)
+		for _, p := range [][2]int{{0, 1}, {1, 0}, {9, 1}, {1, 6}} {
+			if err := v.SetCursorFromScreen(v.x0+p[0], v.y0+p[1]); err != ErrInvalidPoint {
+				t.Errorf("Expected ErrInvalidPoint for %v got: %v", p, err)
+			}
+		}
+	})
+}
+
+func TestSetCursorLogical(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("line%d", i))
+	}
+	lines[15] = "a line longer than the view"
+
+	tests := []struct {
+		name           string
+		col, line      int
+		wantX, wantY   int
+		wantOX, wantOY int
+	}{
+		{"visible", 2, 3, 2, 3, 0, 0},
+		{"below", 1, 12, 1, 12, 0, 8},
+		{"far right", 20, 15, 20, 15, 11, 11},
+		{"clipped", 30, 40, 6, 19, 0, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(10, 5, lines...)
+			if err := v.SetCursorLogical(tt.col, tt.line); err != nil {
+				t.Fatal(err)
+			}
+			if x, y := v.Cursor(); x != tt.wantX || y != tt.wantY {
+				t.Errorf("Expected cursor to be at (%d, %d) got: (%d, %d)", tt.wantX, tt.wantY, x, y)
+			}
+			if ox, oy := v.Origin(); ox != tt.wantOX || oy != tt.wantOY {
+				t.Errorf("Expected origin to be (%d, %d) got: (%d, %d)", tt.wantOX, tt.wantOY, ox, oy)
+			}
+		})
+	}
+
+	t.Run("above", func(t *testing.T) {
+		v := newTestView(10, 5, lines...)
+		v.oy = 10
+		if err := v.SetCursorLogical(0, 2); err != nil {
+			t.Fatal(err)
+		}
+		if _, oy := v.Origin(); oy != 2 {
+			t.Errorf("Expected y-origin 2 got: %d", oy)
+		}
+	})
+
+	t.Run("negative", func(t *testing.T) {
+		v := newTestView(10, 5, lines...)
+		if err := v.SetCursorLogical(-1, 2); err != ErrInvalidPoint {
+			t.Errorf("Expected ErrInvalidPoint got: %v", err)
+		}
+	})
+}
+
+func TestScrollPage(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("line%d", i))
+	}
+
+	tests := []struct {
+		name           string
+		oy, cy         int
+		delta          int
+		wantOy, wantCy int
+	}{
+		{"down", 0, 1, 1, 3, 4},
+		{"two pages down", 0, 0, 2, 6, 6},
+		{"down at the bottom", 6, 8, 1, 7, 9},
+		{"down past the bottom", 3, 3, 5, 7, 7},
+		{"up", 6, 7, -1, 3, 4},
+		{"up at the top", 2, 3, -1, 0, 1},
+		{"cursor off screen", 0, 9, 1, 3, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(10, 3, lines...)
+			v.oy, v.cx, v.cy = tt.oy, 2, tt.cy
+			v.ScrollPage(tt.delta)
+			if v.oy != tt.wantOy || v.cy != tt.wantCy || v.cx != 2 {
+				t.Errorf("Expected origin y %d cursor (2, %d) got: %d (%d, %d)",
+					tt.wantOy, tt.wantCy, v.oy, v.cx, v.cy)
+			}
+		})
+	}
+
+	// Each line is wrapped into two rows
+	v := newTestView(3, 2, "aaaaaa", "bbbbbb", "cccccc")
+	v.Wrap = true
+	v.cx, v.cy = 1, 0
+	v.ScrollPage(1)
+	if v.oy != 2 || v.cx != 1 || v.cy != 1 {
+		t.Errorf("Expected origin y 2 cursor (1, 1) got: %d (%d, %d)", v.oy, v.cx, v.cy)
+	}
+	v.ScrollPage(1)
+	if v.oy != 4 || v.cx != 1 || v.cy != 2 {
+		t.Errorf("Expected origin y 4 cursor (1, 2) got: %d (%d, %d)", v.oy, v.cx, v.cy)
+	}
+	v.ScrollPage(-1)
+	if v.oy != 2 || v.cx != 1 || v.cy != 1 {
+		t.Errorf("Expected origin y 2 cursor (1, 1) got: %d (%d, %d)", v.oy, v.cx, v.cy)
+	}
+}
+
+func TestScrollHalfPage(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < 20; i++ {
+		lines = append(lines, fmt.Sprintf("line%d", i))
+	}
+
+	for _, height := range []int{1, 4, 5} {
+		half := (height + 1) / 2
+		v := newTestView(10, height, lines...)
+		v.ScrollHalfPageDown()
+		if v.oy != half || v.cy != half {
+			t.Errorf("height %d: Expected origin y and cursor y %d got: %d %d", height, half, v.oy, v.cy)
+		}
+		v.ScrollHalfPageUp()
+		if v.oy != 0 || v.cy != 0 {
+			t.Errorf("height %d: Expected origin y and cursor y 0 got: %d %d", height, v.oy, v.cy)
+		}
+
+		for i := 0; i < 30; i++ {
+			v.ScrollHalfPageDown()
+		}
+		if want := len(lines) - height; v.oy != want {
+			t.Errorf("height %d: Expected origin y %d at the bottom got: %d", height, want, v.oy)
+		}
+		if v.cy < 0 || v.cy >= len(lines) {
+			t.Errorf("height %d: Expected cursor within the buffer got: %d", height, v.cy)
+		}
+	}
+}
+
+// viewRow returns the runes drawn on the row y of the content area of v,
+// line numbers gutter included.
+func viewRow(v *View, y int) string {
+	row := []rune{}
+	for x := 0; x < v.x1-v.x0-1; x++ {
+		ch, _ := viewCell(v, x, y)
+		row = append(row, ch)
+	}
+	return string(row)
+}
+
+func TestShowLineNumbers(t *testing.T) {
+	lines := []string{}
+	for i := 1; i <= 12; i++ {
+		lines = append(lines, fmt.Sprintf("line%d", i))
+	}
+	v := newTestView(10, 3, lines...)
+	v.ShowLineNumbers = true
+	if maxX, _ := v.Size(); maxX != 7 {
+		t.Errorf("Expected the content area to be 7 columns wide got: %d", maxX)
+	}
+
+	v.SetOrigin(0, 8)
+	drawTestView(t, v)
+	for y, want := range []string{" 9 line9  ", "10 line10 ", "11 line11 "} {
+		if got := viewRow(v, y); got != want {
+			t.Errorf("Expected row %d to be %q got: %q", y, want, got)
+		}
+	}
+
+	// Clicks are relative to the content area, the gutter is column 0
+	if err := v.SetCursorFromScreen(v.x0+1+3+2, v.y0+2); err != nil {
+		t.Fatal(err)
+	}
+	if cx, cy := v.Cursor(); cx != 2 || cy != 9 {
+		t.Errorf("Expected cursor to be at (2, 9) got: (%d, %d)", cx, cy)
+	}
+	if err := v.SetCursorFromScreen(v.x0+1, v.y0+1); err != nil {
+		t.Fatal(err)
+	}
+	if cx, cy := v.Cursor(); cx != 0 || cy != 8 {
+		t.Errorf("Expected cursor to be at (0, 8) got: (%d, %d)", cx, cy)
+	}
+
+	// Only the first row of a wrapped line is numbered
+	v = newTestView(6, 3, "abcdefgh", "ij")
+	v.ShowLineNumbers = true
+	v.Wrap = true
+	drawTestView(t, v)
+	for y, want := range []string{"1 abcd", "  efgh", "2 ij  "} {
+		if got := viewRow(v, y); got != want {
+			t.Errorf("Expected row %d to be %q got: %q", y, want, got)
+		}
+	}
+}
+
+func TestRelativeLineNumbers(t *testing.T) {
+	v := newTestView(10, 4, "one", "two is long", "three", "four")
+	v.ShowLineNumbers = true
+	v.RelativeLineNumbers = true
+	v.Wrap = true
+	v.SetCursor(0, 2)
+	drawTestView(t, v)
+	for y, want := range []string{"2 one     ", "1 two is l", "  ong     ", "3 three   "} {
+		if got := viewRow(v, y); got != want {
+			t.Errorf("Expected row %d to be %q got: %q", y, want, got)
+		}
+	}
+
+	v.MoveCursor(0, -2)
+	drawTestView(t, v)
+	for y, want := range []string{"1 one     ", "1 two is l", "  ong     ", "2 three   "} {
+		if got := viewRow(v, y); got != want {
+			t.Errorf("Expected row %d to be %q got: %q", y, want, got)
+		}
+	}
+}
+
+func TestShowWhitespace(t *testing.T) {
+	v := newTestView(12, 2, "a b\tc  ", "\td")
+	v.TabWidth = 4
+	v.ShowWhitespace = true
+	v.TrailingWhitespaceBgColor = ColorRed
+	drawTestView(t, v)
+	for y, want := range []string{"a·b→c··     ", "→   d       "} {
+		if got := viewRow(v, y); got != want {
+			t.Errorf("Expected row %d to be %q got: %q", y, want, got)
+		}
+	}
+
+	space := getTcellStyle(ColorDefault|AttrDim, ColorDefault, OutputNormal)
+	trailing := getTcellStyle(ColorDefault|AttrDim, ColorRed, OutputNormal)
+	for _, p := range []struct {
+		x, y int
+		want tcell.Style
+	}{{1, 0, space}, {3, 0, space}, {5, 0, trailing}, {6, 0, trailing}, {0, 1, space}} {
+		if _, st := viewCell(v, p.x, p.y); st != p.want {
+			t.Errorf("Expected cell (%d, %d) style to be %v got: %v", p.x, p.y, p.want, st)
+		}
+	}
+
+	// The buffer and the cursor positions are the real runes
+	if got := v.Buffer(); got != "a b\tc  \n\td" {
+		t.Errorf("Expected buffer to be unchanged got: %q", got)
+	}
+	if err := v.SetCursorFromScreen(v.x0+1+4, v.y0+1); err != nil {
+		t.Fatal(err)
+	}
+	if cx, _ := v.Cursor(); cx != 4 {
+		t.Errorf("Expected cursor to be on the c got: %d", cx)
+	}
+}
+
+func TestHighlightCurrentLine(t *testing.T) {
+	v := newTestView(4, 4, "ab", "cdefgh", "ij")
+	v.Wrap = true
+	v.HighlightCurrentLine = true
+	v.CurrentLineBgColor = ColorBlue
+	v.lines.line(1)[0].fgColor = ColorRed
+
+	_, blue, _ := getTcellStyle(ColorDefault, ColorBlue, OutputNormal).Decompose()
+	highlighted := func(y int) bool {
+		for x := 0; x < 4; x++ {
+			_, st := viewCell(v, x, y)
+			if _, bg, _ := st.Decompose(); bg != blue {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, tt := range []struct {
+		dy   int
+		want []bool
+	}{
+		{0, []bool{true, false, false, false}},
+		{1, []bool{false, true, true, false}},
+		{1, []bool{false, false, false, true}},
+	} {
+		v.MoveCursor(0, tt.dy)
+		drawTestView(t, v)
+		for y, want := range tt.want {
+			if got := highlighted(y); got != want {
+				t.Errorf("Cursor on line %d: expected row %d highlighted to be %v", v.cy, y, want)
+			}
+		}
+	}
+
+	// The colors of the text are kept
+	v.MoveCursor(0, -1)
+	drawTestView(t, v)
+	if _, st := viewCell(v, 0, 1); st != getTcellStyle(ColorRed, ColorBlue, OutputNormal) {
+		t.Errorf("Expected the text color to be kept got: %v", st)
+	}
+}
+
+func TestScrollHorizontal(t *testing.T) {
+	v := newTestView(5, 3, "0123456789abcdef", "short")
+	v.SetCursor(2, 0)
+
+	v.ScrollRight(4)
+	if ox, _ := v.Origin(); ox != 4 {
+		t.Errorf("Expected x-origin 4 got: %d", ox)
+	}
+	if cx, cy := v.Cursor(); cx != 4 || cy != 0 {
+		t.Errorf("Expected cursor to follow to (4, 0) got: (%d, %d)", cx, cy)
+	}
+
+	// Clamped at the end of the longest line
+	v.ScrollRight(100)
+	if ox, _ := v.Origin(); ox != 11 {
+		t.Errorf("Expected x-origin 11 got: %d", ox)
+	}
+	v.SetCursor(15, 0)
+
+	v.ScrollLeft(3)
+	if ox, _ := v.Origin(); ox != 8 {
+		t.Errorf("Expected x-origin 8 got: %d", ox)
+	}
+	if cx, _ := v.Cursor(); cx != 12 {
+		t.Errorf("Expected cursor to follow to 12 got: %d", cx)
+	}
+
+	// Clamped at the start of the lines
+	v.ScrollLeft(100)
+	if ox, _ := v.Origin(); ox != 0 {
+		t.Errorf("Expected x-origin 0 got: %d", ox)
+	}
+	if cx, _ := v.Cursor(); cx != 4 {
+		t.Errorf("Expected cursor to follow to 4 got: %d", cx)
+	}
+
+	v.Wrap = true
+	v.ScrollRight(2)
+	if ox, _ := v.Origin(); ox != 0 {
+		t.Errorf("Expected no horizontal scroll with Wrap got: %d", ox)
+	}
+}
+
+func TestCenterCursor(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < 100; i++ {
+		lines = append(lines, fmt.Sprintf("line%d", i))
+	}
+
+	tests := []struct {
+		name   string
+		cy     int
+		wantOy int
+	}{
+		{"top", 1, 0},
+		{"middle", 50, 48},
+		{"bottom", 98, 95},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(10, 5, lines...)
+			v.cy = tt.cy
+			v.CenterCursor()
+			if v.oy != tt.wantOy {
+				t.Errorf("Expected origin y %d got: %d", tt.wantOy, v.oy)
+			}
+		})
+	}
+
+	// Each line is wrapped into two rows, the cursor is on the row 21
+	v := newTestView(3, 5, lines...)
+	v.Wrap = true
+	v.cx, v.cy = 4, 10
+	v.CenterCursor()
+	if v.oy != 19 {
+		t.Errorf("Expected origin y 19 with wrapped lines got: %d", v.oy)
+	}
+}
+
+func TestMask(t *testing.T) {
+	v := newTestView(10, 2, "p\tw中d")
+	v.Mask = '•'
+	v.Editable = true
+	v.SetCursor(5, 0)
+	v.EditDelete(true)
+	v.MoveCursor(-2, 0)
+	v.EditWrite('x')
+	drawTestView(t, v)
+
+	for x, want := range []rune("•••••     ") {
+		if got, _ := viewCell(v, x, 0); got != want {
+			t.Errorf("Expected %q at column %d got: %q", want, x, got)
+		}
+	}
+	if got, want := v.Buffer(), "p\txw中"; got != want {
+		t.Errorf("Expected the buffer to be: %q got: %q", want, got)
+	}
+	if got, _ := v.Line(0); got != "p\txw中" {
+		t.Errorf("Expected the line to be: %q got: %q", "p\txw中", got)
+	}
+	if x, _, _ := v.linesPosOnScreen(v.cx, v.cy); x != 3 {
+		t.Errorf("Expected the cursor on the column 3 got: %d", x)
+	}
+}
+
+func TestPlaceholder(t *testing.T) {
+	v := newTestView(10, 2)
+	v.Editable = true
+	v.Placeholder = "Search"
+	drawTestView(t, v)
+
+	for x, want := range "Search  " {
+		ch, st := viewCell(v, x, 0)
+		if ch != want {
+			t.Errorf("Expected %q at column %d got: %q", want, x, ch)
+		}
+		if _, _, attrs := st.Decompose(); x < 6 && attrs&tcell.AttrDim == 0 {
+			t.Errorf("Expected the placeholder to be dimmed at column %d", x)
+		}
+	}
+	if got := v.Buffer(); got != "" {
+		t.Errorf("Expected an empty buffer got: %q", got)
+	}
+	if v.cx != 0 || v.cy != 0 {
+		t.Errorf("Expected the cursor at (0, 0) got: (%d, %d)", v.cx, v.cy)
+	}
+
+	v.EditWrite('a')
+	drawTestView(t, v)
+	for x, want := range "a       " {
+		if ch, _ := viewCell(v, x, 0); ch != want {
+			t.Errorf("Expected %q at column %d after typing got: %q", want, x, ch)
+		}
+	}
+
+	// An empty line left by deleting the text shows the placeholder again
+	v.EditDelete(true)
+	drawTestView(t, v)
+	if ch, _ := viewCell(v, 0, 0); ch != 'S' {
+		t.Errorf("Expected the placeholder after deleting the text got: %q", ch)
+	}
+}
+
+func TestAlignment(t *testing.T) {
+	tests := []struct {
+		line      string
+		alignment Alignment
+		want      int
+	}{
+		{"abc", AlignLeft, 0},
+		{"abc", AlignCenter, 3},
+		{"abcd", AlignCenter, 3},
+		{"abc", AlignRight, 7},
+		{"", AlignRight, 10},
+		{"中文", AlignCenter, 3},
+		{"abcdefghij", AlignCenter, 0},
+		{"abcdefghijkl", AlignRight, 0},
+	}
+
+	for _, tt := range tests {
+		v := newTestView(10, 2, tt.line)
+		v.Alignment = tt.alignment
+		if got := v.alignOffset(v.lines.line(0)); got != tt.want {
+			t.Errorf("%q aligned %d: Expected an offset of %d got: %d", tt.line, tt.alignment, tt.want, got)
+		}
+		v.Editable = true
+		if got := v.alignOffset(v.lines.line(0)); got != 0 {
+			t.Errorf("%q aligned %d: Expected no offset in an editable view got: %d", tt.line, tt.alignment, got)
+		}
+	}
+
+	v := newTestView(7, 2, "ab", "abcd")
+	v.Alignment = AlignRight
+	drawTestView(t, v)
+	for y, want := range []string{"     ab", "   abcd"} {
+		for x, r := range want {
+			if ch, _ := viewCell(v, x, y); ch != r {
+				t.Errorf("Expected %q at (%d, %d) got: %q", r, x, y, ch)
+			}
+		}
+	}
+	if err := v.SetCursorFromScreen(7, 1); err != nil || v.cx != 1 || v.cy != 0 {
+		t.Errorf("Expected a click on the b to move the cursor to (1, 0) got: (%d, %d) %v", v.cx, v.cy, err)
+	}
+}
+
+func TestVAlign(t *testing.T) {
+	tests := []struct {
+		lines  []string
+		valign VerticalAlignment
+		want   int
+	}{
+		{[]string{"a"}, AlignTop, 0},
+		{[]string{"a"}, AlignMiddle, 2},
+		{[]string{"a", "b"}, AlignMiddle, 1},
+		{[]string{"a", ""}, AlignBottom, 4},
+		{[]string{"a", "b"}, AlignBottom, 3},
+		{[]string{"a", "b", "c", "d", "e", "f"}, AlignMiddle, 0},
+	}
+
+	for _, tt := range tests {
+		v := newTestView(10, 5, tt.lines...)
+		v.VAlign = tt.valign
+		if got := v.valignOffset(v.viewLines()); got != tt.want {
+			t.Errorf("%q aligned %d: Expected an offset of %d got: %d", tt.lines, tt.valign, tt.want, got)
+		}
+		v.Editable = true
+		if got := v.valignOffset(v.viewLines()); got != 0 {
+			t.Errorf("%q aligned %d: Expected no offset in an editable view got: %d", tt.lines, tt.valign, got)
+		}
+	}
+
+	v := newTestView(5, 5, "hi")
+	v.Alignment, v.VAlign = AlignCenter, AlignMiddle
+	drawTestView(t, v)
+	if ch, _ := viewCell(v, 1, 2); ch != 'h' {
+		t.Errorf("Expected the text in the middle of the view got: %q", ch)
+	}
+	if err := v.SetCursorFromScreen(3, 3); err != nil || v.cx != 1 || v.cy != 0 {
+		t.Errorf("Expected a click on the i to move the cursor to (1, 0) got: (%d, %d) %v", v.cx, v.cy, err)
+	}
+
+	// Editable views are drawn at the top
+	v.Editable = true
+	drawTestView(t, v)
+	if ch, _ := viewCell(v, 0, 0); ch != 'h' {
+		t.Errorf("Expected the text of an editable view at the top left got: %q", ch)
+	}
+}
+
+func BenchmarkDraw(b *testing.B) {
+	if err := tcellInitSimulation(); err != nil {
+		b.Fatal(err)
+	}
+	lines := []string{}
+	for i := 0; i < 25; i++ {
+		lines = append(lines, strings.Repeat("gocui ", 13))
+	}
+
+	// A changed rune taints the view, the whole content is laid out again
+	// but tcell only writes the changed cell to the terminal on Show
+	b.Run("single change", func(b *testing.B) {
+		v := newTestView(80, 25, lines...)
+		for i := 0; i < b.N; i++ {
+			v.lines.line(12)[40].chr = rune('a' + i%26)
+			v.tainted = true
+			if err := v.draw(); err != nil {
+				b.Fatal(err)
+			}
+			screen.Show()
+		}
+	})
+	b.Run("unchanged", func(b *testing.B) {
+		v := newTestView(80, 25, lines...)
+		for i := 0; i < b.N; i++ {
+			if err := v.draw(); err != nil {
+				b.Fatal(err)
+			}
+			screen.Show()
+		}
+	})
+}
+
+func TestDrawCache(t *testing.T) {
+	v := newTestView(5, 2, "hello", "world")
+	drawTestView(t, v)
+	if v.IsTainted() {
+		t.Error("Expected the view not to be tainted after drawing it")
+	}
+
+	// Settings changed without tainting the view are drawn too
+	v.SetOrigin(1, 1)
+	drawTestView(t, v)
+	if ch, _ := viewCell(v, 0, 0); ch != 'o' {
+		t.Errorf("Expected %q drawn after moving the origin got: %q", 'o', ch)
+	}
+	v.Mask = '*'
+	drawTestView(t, v)
+	if ch, _ := viewCell(v, 3, 0); ch != '*' {
+		t.Errorf("Expected %q drawn after masking the view got: %q", '*', ch)
+	}
+}
+
+func TestMaxLineWidth(t *testing.T) {
+	tests := []struct {
+		lines    []string
+		tabWidth int
+		want     int
+	}{
+		{nil, 0, 0},
+		{[]string{"abc", "de"}, 0, 3},
+		{[]string{"a\tb", "abcdef"}, 0, 9},
+		{[]string{"a\tb", "abcdef"}, 4, 6},
+		{[]string{"\t\t"}, 2, 4},
+		{[]string{"世界", "abc"}, 0, 4},
+		{[]string{"世\tx"}, 4, 5},
+	}
+	for _, tt := range tests {
+		v := newTestView(10, 5, tt.lines...)
+		v.TabWidth = tt.tabWidth
+		if got := v.MaxLineWidth(); got != tt.want {
+			t.Errorf("%q tab width %d: expected %d got: %d", tt.lines, tt.tabWidth, tt.want, got)
+		}
+	}
+
+	// The cached width follows the changes of the buffer
+	v := newTestView(10, 5, "ab")
+	for _, tt := range []struct {
+		name string
+		f    func()
+		want int
+	}{
+		{"Write", func() { fmt.Fprint(v, "\n世界世") }, 6},
+		{"EditDeleteLine", func() { v.SetCursor(0, 1); v.EditDeleteLine() }, 2},
+		{"Undo", func() { v.Undo() }, 6},
+		{"SetLine", func() { _ = v.SetLine(1, "a\tb") }, 9},
+		{"TabWidth", func() { v.TabWidth = 2 }, 3},
+		{"AppendLine", func() { v.AppendLine("abcde") }, 5},
+		{"SetContent", func() { v.SetContent([]string{"abcd"}) }, 4},
+		{"SetCell", func() { _ = v.SetCell(6, 0, 'x', ColorDefault, ColorDefault) }, 7},
+	} {
+		v.MaxLineWidth()
+		tt.f()
+		if got := v.MaxLineWidth(); got != tt.want {
+			t.Errorf("After %s, expected %d got: %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestLineCount(t *testing.T) {
+	tests := []struct {
+		wrap      bool
+		lines     []string
+		tabWidth  int
+		wantLines int
+		wantRows  int
+	}{
+		{false, nil, 0, 0, 0},
+		{false, []string{"abcdefghijkl", "ab", "c"}, 0, 3, 3},
+		{true, []string{"abcdefghijkl", "ab", "c"}, 0, 3, 5},
+		{true, []string{"abcde", "世界世"}, 0, 2, 3},
+		{false, []string{"abc\tdefg"}, 4, 1, 1},
+		{true, []string{"abc\tdefg"}, 4, 1, 2},
+	}
+	for _, tt := range tests {
+		v := newTestView(5, 10, tt.lines...)
+		v.Wrap = tt.wrap
+		v.TabWidth = tt.tabWidth
+		if got := v.LineCount(); got != tt.wantLines {
+			t.Errorf("%q wrap %v: expected %d lines got: %d", tt.lines, tt.wrap, tt.wantLines, got)
+		}
+		got := v.VisualRowCount()
+		if got != tt.wantRows {
+			t.Errorf("%q wrap %v: expected %d rows got: %d", tt.lines, tt.wrap, tt.wantRows, got)
+		}
+
+		// The rows are the ones that are drawn
+		drawTestView(t, v)
+		for y := 0; y < 10; y++ {
+			if empty := strings.TrimSpace(viewRow(v, y)) == ""; empty != (y >= got) {
+				t.Errorf("%q wrap %v: row %d is %q with %d rows", tt.lines, tt.wrap, y, viewRow(v, y), got)
+			}
+		}
+	}
+}
+
+func TestClearKeepCursor(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf("line%d", i))
+	}
+	v := newTestView(10, 3, lines...)
+	v.SetCursor(3, 6)
+	v.SetOrigin(0, 5)
+	drawTestView(t, v)
+
+	v.ClearKeepCursor()
+	if n := v.LineCount(); n != 0 {
+		t.Errorf("Expected an empty buffer got %d lines", n)
+	}
+	assertCursorOrigin := func(cx, cy, ox, oy int) {
+		t.Helper()
+		if x, y := v.Cursor(); x != cx || y != cy {
+			t.Errorf("Expected the cursor at (%d, %d) got: (%d, %d)", cx, cy, x, y)
+		}
+		if x, y := v.Origin(); x != ox || y != oy {
+			t.Errorf("Expected the origin at (%d, %d) got: (%d, %d)", ox, oy, x, y)
+		}
+	}
+	assertCursorOrigin(0, 0, 0, 0)
+
+	// The cursor follows the content up to its position
+	fmt.Fprintln(v, "a")
+	fmt.Fprintln(v, "bc")
+	assertCursorOrigin(0, 2, 0, 0)
+	for _, line := range lines[2:] {
+		fmt.Fprintln(v, line)
+	}
+	assertCursorOrigin(3, 6, 0, 4)
+
+	// It's not moved again once moved by something else
+	v.SetCursor(1, 1)
+	fmt.Fprintln(v, "more")
+	assertCursorOrigin(1, 1, 0, 4)
+
+	// Clear forgets the position
+	v.SetCursor(3, 6)
+	v.ClearKeepCursor()
+	v.Clear()
+	fmt.Fprint(v, strings.Join(lines, "\n"))
+	assertCursorOrigin(0, 0, 0, 0)
+}
+
+func TestClearKeepCursorMoveNotification(t *testing.T) {
+	v := newTestView(10, 3, "a", "b", "c")
+	v.SetCursor(0, 2)
+	v.ClearKeepCursor()
+	moves := 0
+	v.OnCursorMove = func(v *View, oldX, oldY, newX, newY int) {
+		moves++
+		// The buffer isn't locked
+		v.Buffer()
+	}
+	fmt.Fprintln(v, "a")
+	fmt.Fprintln(v, "b")
+	if moves != 2 {
+		t.Errorf("Expected 2 cursor moves got %d", moves)
+	}
+	fmt.Fprintln(v, "c")
+	if moves != 2 {
+		t.Errorf("Expected no more cursor moves got %d", moves)
+	}
+}
+
+func TestClearKeepCursorMaxLines(t *testing.T) {
+	v := newTestView(10, 3, "a", "b", "c", "d")
+	v.MaxLines = 4
+	v.SetCursor(0, 3)
+	v.ClearKeepCursor()
+	fmt.Fprint(v, "a\nb\nc\nd\ne\nf")
+	// The kept position follows the lines dropped from the top
+	if x, y := v.Cursor(); x != 0 || y != 1 {
+		t.Errorf("Expected the cursor at (0, 1) got: (%d, %d)", x, y)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+// vimMode is the mode of the vim editor.
+type vimMode int
+
+const (
+	vimNormal vimMode = iota
+	vimInsert
+	vimVisual
+)
+
+// vimEditor is a modal editor with a subset of the vim keybindings.
+type vimEditor struct {
+	mode vimMode
+
+	// pending is the first key of a two keys command (e.g. dd)
+	pending rune
+
+	// anchorX and anchorY are the start of the visual selection
+	anchorX, anchorY int
+}
+
+// NewVimEditor returns an Editor that mimics vim. It starts in normal mode,
+// where the keys move the cursor and edit the buffer:
+//
+//	h, j, k, l  move the cursor left, down, up and right
+//	w, b        move the cursor to the next or previous word
+//	0, $        move the cursor to the start or end of the line
+//	ctrl+D, U   scroll half a page down or up
+//	x           delete the rune under the cursor
+//	dd          delete the current line
+//	u           undo the last edit
+//	i, a        enter insert mode before or after the cursor
+//	o, O        open a line below or above and enter insert mode
+//	v           enter visual mode
+//
+// In insert mode the keys are handled like with DefaultEditor. In visual
+// mode the motions extend the selection, d or x delete it and y copies it to
+// the kill ring. Esc goes back to normal mode.
+//
+// Every view needs its own editor, as the mode is kept by the editor.
+func NewVimEditor() Editor {
+	return &vimEditor{}
+}
+
+// Edit handles a key press according to the current mode.
+func (e *vimEditor) Edit(v *View, key Key, ch rune, mod Modifier) {
+	v.beginEdit()
+	defer v.endEdit()
+
+	switch e.mode {
+	case vimInsert:
+		if key == KeyEsc {
+			e.mode = vimNormal
+			return
+		}
+		simpleEditor(v, key, ch, mod)
+	case vimVisual:
+		e.editVisual(v, key, ch)
+	default:
+		e.editNormal(v, key, ch)
+	}
+}
+
+// editNormal handles a key press in normal mode.
+func (e *vimEditor) editNormal(v *View, key Key, ch rune) {
+	pending := e.pending
+	e.pending = 0
+	if pending == 'd' && ch == 'd' {
+		v.EditDeleteLine()
+		return
+	}
+
+	if e.move(v, key, ch) {
+		return
+	}
+
+	switch ch {
+	case 'x':
+		if y := v.cy; y < v.lines.len() && v.cx < len(v.lines.line(y)) {
+			v.EditDelete(false)
+		}
+	case 'd':
+		e.pending = ch
+	case 'u':
+		v.Undo()
+	case 'i':
+		e.mode = vimInsert
+	case 'a':
+		if y := v.cy; y < v.lines.len() && v.cx < len(v.lines.line(y)) {
+			v.MoveCursor(1, 0)
+		}
+		e.mode = vimInsert
+	case 'o':
+		v.EditOpenLineBelow()
+		e.mode = vimInsert
+	case 'O':
+		v.EditOpenLineAbove()
+		e.mode = vimInsert
+	case 'v':
+		e.mode = vimVisual
+		e.anchorX, e.anchorY = v.cx, v.cy
+		e.updateSelection(v)
+	}
+}
+
+// editVisual handles a key press in visual mode.
+func (e *vimEditor) editVisual(v *View, key Key, ch rune) {
+	if e.move(v, key, ch) {
+		e.updateSelection(v)
+		return
+	}
+
+	switch {
+	case key == KeyEsc || ch == 'v':
+	case ch == 'd' || ch == 'x':
+		if s := v.selection; s != nil {
+			v.pushKill(v.SelectedText())
+			if err := v.deleteText(s.x0, s.y0, s.x1, s.y1); err == nil {
+				v.placeCursor(s.x0, s.y0)
+			}
+		}
+	case ch == 'y':
+		v.pushKill(v.SelectedText())
+	default:
+		return
+	}
+	e.mode = vimNormal
+	v.ClearSelection()
+}
+
+// move handles the motion keys, it returns false if the key isn't a motion.
+func (e *vimEditor) move(v *View, key Key, ch rune) bool {
+	switch {
+	case ch == 'h' || key == KeyArrowLeft:
+		if v.cx > 0 {
+			v.MoveCursor(-1, 0)
+		}
+	case ch == 'l' || key == KeyArrowRight:
+		if y := v.cy; y < v.lines.len() && v.cx < len(v.lines.line(y)) {
+			v.MoveCursor(1, 0)
+		}
+	case ch == 'j' || key == KeyArrowDown:
+		v.MoveCursor(0, 1)
+	case ch == 'k' || key == KeyArrowUp:
+		v.MoveCursor(0, -1)
+	case ch == 'w':
+		v.MoveCursorWordRight()
+	case ch == 'b':
+		v.MoveCursorWordLeft()
+	case key == KeyCtrlD:
+		v.ScrollHalfPageDown()
+	case key == KeyCtrlU:
+		v.ScrollHalfPageUp()
+	case ch == '0':
+		v.MoveCursor(-v.cx, 0)
+	case ch == '$':
+		if y := v.cy; y < v.lines.len() {
+			v.MoveCursor(len(v.lines.line(y))-v.cx, 0)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// updateSelection updates the visual selection, it goes from the anchor to the
+// cursor, both included.
+func (e *vimEditor) updateSelection(v *View) {
+	ax, ay, cx, cy := e.anchorX, e.anchorY, v.cx, v.cy
+	if cy < ay || (cy == ay && cx < ax) {
+		v.SetSelection(cx, cy, ax+1, ay)
+	} else {
+		v.SetSelection(ax, ay, cx+1, cy)
+	}
+}
+
@@ -0,0 +1,81 @@
+// Copyright 2021 The gocui Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package gocui
+
+import "testing"
+
+// sendVimKeys feeds the runes of keys to the editor of v, '\x1b' is sent as
+// KeyEsc.
+func sendVimKeys(v *View, keys string) {
+	for _, r := range keys {
+		if r == '\x1b' {
+			v.Editor.Edit(v, KeyEsc, 0, ModNone)
+		} else {
+			v.Editor.Edit(v, 0, r, ModNone)
+		}
+	}
+}
+
+func TestVimEditor(t *testing.T) {
+	tests := []struct {
+		name         string
+		keys         string
+		wantX, wantY int
+		want         []string
+		mode         vimMode
+	}{
+		{"motions don't edit", "jlllhwb", 0, 1, []string{"hello world", "foo bar", "baz"}, vimNormal},
+		{"insert", "ihey \x1b", 4, 0, []string{"hey hello world", "foo bar", "baz"}, vimNormal},
+		{"append", "a!", 2, 0, []string{"h!ello world", "foo bar", "baz"}, vimInsert},
+		{"delete rune", "wxx", 6, 0, []string{"hello rld", "foo bar", "baz"}, vimNormal},
+		{"delete line", "jdd", 0, 1, []string{"hello world", "baz"}, vimNormal},
+		{"delete last line", "jjdd", 0, 1, []string{"hello world", "foo bar"}, vimNormal},
+		{"pending d is reset", "dxdd", 0, 0, []string{"foo bar", "baz"}, vimNormal},
+		{"undo", "xxu", 0, 0, []string{"ello world", "foo bar", "baz"}, vimNormal},
+		{"visual delete", "lvlld", 1, 0, []string{"ho world", "foo bar", "baz"}, vimNormal},
+		{"visual across lines", "wvjd", 6, 0, []string{"hello ", "baz"}, vimNormal},
+		{"visual escape", "vll\x1b", 2, 0, []string{"hello world", "foo bar", "baz"}, vimNormal},
+		{"open line below", "lojk\x1b", 2, 1, []string{"hello world", "jk", "foo bar", "baz"}, vimNormal},
+		{"open line above", "jOjk", 2, 1, []string{"hello world", "jk", "foo bar", "baz"}, vimInsert},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestView(20, 5, "hello world", "foo bar", "baz")
+			v.Editor = NewVimEditor()
+			sendVimKeys(v, tt.keys)
+			assertBuffer(t, v, tt.wantX, tt.wantY, tt.want...)
+			if mode := v.Editor.(*vimEditor).mode; mode != tt.mode {
+				t.Errorf("Expected mode to be %d got: %d", tt.mode, mode)
+			}
+		})
+	}
+}
+
+func TestVimEditorVisualYank(t *testing.T) {
+	v := newTestView(20, 5, "hello world")
+	v.Editor = NewVimEditor()
+	sendVimKeys(v, "wvlly")
+	if got := v.LastKill(); got != "wor" {
+		t.Errorf("Expected yanked text to be: %q got: %q", "wor", got)
+	}
+	if got := v.SelectedText(); got != "" {
+		t.Errorf("Expected selection to be cleared got: %q", got)
+	}
+}
+
+func TestVimEditorScroll(t *testing.T) {
+	lines := []string{"0", "1", "2", "3", "4", "5", "6", "7"}
+	v := newTestView(10, 4, lines...)
+	v.Editor = NewVimEditor()
+	v.Editor.Edit(v, KeyCtrlD, 0, ModNone)
+	if v.oy != 2 || v.cy != 2 {
+		t.Errorf("Expected origin y and cursor y 2 after ctrl+D got: %d %d", v.oy, v.cy)
+	}
+	v.Editor.Edit(v, KeyCtrlU, 0, ModNone)
+	if v.oy != 0 || v.cy != 0 {
+		t.Errorf("Expected origin y and cursor y 0 after ctrl+U got: %d %d", v.oy, v.cy)
+	}
+}